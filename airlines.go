@@ -0,0 +1,292 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type Airline struct {
+	Name        string
+	IATA_Code   string
+	ICAO_Code   string
+	ISO_Country string
+	Website     string
+}
+
+var airlines []Airline
+
+// The bundled airline lookup, used when --airlines is not given
+//
+//go:embed airline-lookup.csv
+var embeddedAirlines string
+
+const embeddedAirlinesPath = "embedded:airline-lookup.csv"
+
+// Set with --airlines
+var airlinesPath string
+
+// Detect pattern @XX1234 (IATA) or @@XXX1234 (ICAO), a flight number after the airline code,
+// @XX 1234 as printed on boarding passes too
+var flightToken = regexp.MustCompile(`@@[A-Z]{3} ?\d{1,4}|@[A-Z0-9]{2} ?\d{1,4}`)
+
+var airlineIATAShape = regexp.MustCompile(`^[A-Z0-9]{2}$`)
+var airlineICAOShape = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func addAirlineFlags(flags *flag.FlagSet) {
+	flags.StringVar(&airlinesPath, "airlines", "", "Airline lookup with name, iata_code, icao_code and iso_country columns, the embedded airline-lookup.csv without one")
+}
+
+func chooseAirlines() string {
+	if airlinesPath == "" {
+		return embeddedAirlinesPath
+	}
+	return airlinesPath
+}
+
+// Columns every airline lookup has, website is optional
+var airlineColumns = []string{"name", "iata_code", "icao_code", "iso_country"}
+
+// Only airline rows have a website to check
+const reasonWebsite = "invalid website"
+
+func webAddress(website string) bool {
+	link, err := url.Parse(website)
+	return err == nil && (link.Scheme == "http" || link.Scheme == "https") && link.Host != ""
+}
+
+// Reads a row of the airline lookup, with the reasons of the airport rows
+func parseAirlineRow(row lookupRecord, columns map[string]int) (Airline, *rowProblem) {
+	if row.width > 0 {
+		return Airline{}, &rowProblem{reasonColumns, fmt.Sprintf("expected %d columns, got %d", row.width, len(row.fields))}
+	}
+	field := func(column string) string {
+		if i, found := columns[column]; found {
+			return strings.TrimSpace(row.fields[i])
+		}
+		return ""
+	}
+
+	for _, column := range airlineColumns {
+		if field(column) == "" {
+			return Airline{}, &rowProblem{reasonBlank, column + " is blank"}
+		}
+	}
+	for _, column := range append(airlineColumns, "website") {
+		if problem := textProblem(field(column)); problem != "" {
+			return Airline{}, &rowProblem{reasonEncoding, fmt.Sprintf("%v %q %v", column, field(column), problem)}
+		}
+	}
+	if !airlineIATAShape.MatchString(field("iata_code")) {
+		return Airline{}, &rowProblem{reasonCodeShape, fmt.Sprintf("IATA code %q is not two capital letters or digits", field("iata_code"))}
+	}
+	if !airlineICAOShape.MatchString(field("icao_code")) {
+		return Airline{}, &rowProblem{reasonCodeShape, fmt.Sprintf("ICAO code %q is not three capital letters", field("icao_code"))}
+	}
+	//The html output links to the website, so only web addresses are taken
+	if website := field("website"); website != "" && !webAddress(website) {
+		return Airline{}, &rowProblem{reasonWebsite, fmt.Sprintf("website %q is not an http or https address", website)}
+	}
+
+	return Airline{
+		Name:        normalizeText(field("name")),
+		IATA_Code:   field("iata_code"),
+		ICAO_Code:   field("icao_code"),
+		ISO_Country: field("iso_country"),
+		Website:     field("website"),
+	}, nil
+}
+
+// An airline row that passed validation
+type airlineRow struct {
+	line    int
+	airline Airline
+}
+
+// Airlines have no place, the same name in the same country is the same airline
+var airlineRowKeys = duplicateKeys[airlineRow]{
+	line:  func(row airlineRow) int { return row.line },
+	codes: func(row airlineRow) (string, string) { return row.airline.IATA_Code, row.airline.ICAO_Code },
+	same: func(a, b airlineRow) bool {
+		return strings.EqualFold(a.airline.Name, b.airline.Name) &&
+			a.airline.ISO_Country == b.airline.ISO_Country &&
+			a.airline.IATA_Code == b.airline.IATA_Code &&
+			a.airline.ICAO_Code == b.airline.ICAO_Code
+	},
+}
+
+// Reads an airline lookup, the columns may come in any order and website is optional.
+// Rows are checked like the airport ones and rows sharing a code settled by --duplicates
+func loadAirlines(path string) error {
+	content := embeddedAirlines
+	if path != embeddedAirlinesPath {
+		var err error
+		if content, err = loadFile(path); err != nil {
+			return &lookupFileError{path, fmt.Errorf("%w: %v", errLookupNotFound, err)}
+		}
+	}
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	var records []lookupRecord
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &lookupFileError{path, fmt.Errorf("%w: error reading CSV: %v", errLookupMalformed, err)}
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, lookupRecord{line: line, fields: fields})
+	}
+	if len(records) == 0 {
+		return &lookupFileError{path, fmt.Errorf("%w: the airline lookup is empty", errLookupMalformed)}
+	}
+
+	columns := map[string]int{}
+	for i, head := range records[0].fields {
+		columns[strings.TrimSpace(head)] = i
+	}
+	var missing []string
+	for _, required := range airlineColumns {
+		if _, found := columns[required]; !found {
+			missing = append(missing, required)
+		}
+	}
+	if len(missing) > 0 {
+		return &lookupFileError{path, fmt.Errorf("%w: missing columns %v in the airline header", errLookupMalformed, strings.Join(missing, ", "))}
+	}
+
+	//Skip invalid rows and count them by reason
+	skipped := map[string]int{}
+	var reasons []string
+	var rows []airlineRow
+	for _, record := range records[1:] {
+		if len(record.fields) != len(records[0].fields) {
+			record.width = len(records[0].fields)
+		}
+		airline, problem := parseAirlineRow(record, columns)
+		if problem != nil {
+			if skipped[problem.reason] == 0 {
+				reasons = append(reasons, problem.reason)
+			}
+			skipped[problem.reason]++
+			continue
+		}
+		rows = append(rows, airlineRow{record.line, airline})
+	}
+
+	rows, conflicts, err := applyDuplicatePolicy(rows, duplicatePolicy, airlineRowKeys)
+	if err != nil {
+		return &lookupFileError{path, err}
+	}
+	var loaded []Airline
+	for _, row := range rows {
+		loaded = append(loaded, row.airline)
+	}
+	airlines = loaded
+
+	if len(conflicts) > 0 {
+		report(Diagnostic{Code: diagLookupDuplicate, Severity: severityWarning, File: path,
+			Message: fmt.Sprintf("%d airline codes are used by more than one row, kept the %v one", len(conflicts), duplicatePolicy)})
+	}
+	for _, reason := range reasons {
+		report(Diagnostic{Code: diagLookupRow, Severity: severityWarning, File: path,
+			Message: fmt.Sprintf("could not read %d airline records, %v", skipped[reason], reason)})
+	}
+	return nil
+}
+
+// Splits @BA283 or @BA 283 into BA and 283, or @@BAW283 into BAW and 283
+func splitFlight(token string) (code string, number string, icao bool) {
+	icao = strings.HasPrefix(token, "@@")
+	rest := strings.TrimLeft(token, "@")
+	size := 2
+	if icao {
+		size = 3
+	}
+	return rest[:size], strings.TrimSpace(rest[size:]), icao
+}
+
+// An airline code has a letter, @12345 is only a number
+func airlineCodeShape(code string) bool {
+	return strings.Trim(code, "0123456789") != ""
+}
+
+func findAirlineByToken(token string) (Airline, string, bool) {
+	code, number, icao := splitFlight(token)
+	if !airlineCodeShape(code) {
+		return Airline{}, number, false
+	}
+	for _, airline := range airlines {
+		if (!icao && airline.IATA_Code == code) || (icao && airline.ICAO_Code == code) {
+			return airline, number, true
+		}
+	}
+	return Airline{}, number, false
+}
+
+// Where the html output links an airline to, its website or a search for it
+func airlineLink(airline Airline) string {
+	if airline.Website != "" {
+		return airline.Website
+	}
+	return "https://www.google.com/search?q=" + url.QueryEscape(airline.Name)
+}
+
+// Replaces @BA283 with British Airways 283, render writes the airline for each output
+func placeFlightsWith(input string, render func(airline Airline) string) string {
+	return replaceMatches(flightToken, input, func(match string, start, end int) string {
+		//A third @ is a typo like a third #
+		if (start > 0 && input[start-1] == '@') || gluedCode(input, start, end) {
+			return match
+		}
+		airline, number, found := findAirlineByToken(match)
+		if !found {
+			return match
+		}
+		return render(airline) + " " + number
+	})
+}
+
+func placeFlights(input string) string {
+	return placeFlightsWith(input, airlineText)
+}
+
+func airlineText(airline Airline) string {
+	return airline.Name
+}
+
+func placeFlightsHTML(input string) string {
+	return placeFlightsWith(input, airlineHTML)
+}
+
+func airlineHTML(airline Airline) string {
+	return "<a href=\"" + escapeHTML(airlineLink(airline)) + "\" target=\"_blank\">" + escapeHTML(airline.Name) + "</a>"
+}
+
+// Airlines whose code of the same kind is one letter away
+func suggestAirlines(token string) []suggestion {
+	code, _, icao := splitFlight(token)
+
+	var suggestions []suggestion
+	for _, airline := range airlines {
+		known, prefix := airline.IATA_Code, "@"
+		if icao {
+			known, prefix = airline.ICAO_Code, "@@"
+		}
+		if editDistance(code, known) == 1 {
+			suggestions = append(suggestions, suggestion{Code: prefix + known, Name: airline.Name})
+		}
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+	}
+	return suggestions
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPlaceFlights(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"@BA283", "British Airways 283"},
+		{"@@BAW283", "British Airways 283"},
+		{"(@AY1331)", "(Finnair 1331)"},
+		{"@AY 1331", "Finnair 1331"},
+		{"@@FIN 1331", "Finnair 1331"},
+		{"@AY 13312", "@AY 13312"},
+		{"@U2123", "easyJet 123"},
+		{"@ZZ100", "@ZZ100"},
+		{"@12345", "@12345"},
+		{"info@BA283", "info@BA283"},
+		{"@BA283X", "@BA283X"},
+		{"@ba283", "@ba283"},
+		{"@@BA283", "@@BA283"},
+	}
+	for _, test := range tests {
+		if got := placeFlights(test.input); got != test.want {
+			t.Errorf("placeFlights(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+
+	html := placeFlightsHTML("@BA283 @AY1331")
+	for _, want := range []string{
+		`<a href="https://www.britishairways.com" target="_blank">British Airways</a> 283`,
+		`<a href="https://www.google.com/search?q=Finnair" target="_blank">Finnair</a> 1331`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("placeFlightsHTML = %q, missing %q", html, want)
+		}
+	}
+	if got := airlineLink(Airline{Name: "Air Dolomiti & Co/Ltd"}); got != "https://www.google.com/search?q=Air+Dolomiti+%26+Co%2FLtd" {
+		t.Errorf("airlineLink = %q, want the name query escaped", got)
+	}
+}
+
+func TestUnknownAirlines(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	unresolved := findUnresolvedCodes("Flights @BA283 @BX 100 @@BAX7 @12345\n#ZZZ")
+	var got []string
+	for _, code := range unresolved {
+		got = append(got, code.Kind+" "+code.Code)
+	}
+	if strings.Join(got, ", ") != "airline @BX, airline @@BAX, airport #ZZZ" {
+		t.Fatalf("unresolved codes = %v", got)
+	}
+	if len(unresolved[0].Suggestions) == 0 || unresolved[0].Suggestions[0].Code != "@BA" {
+		t.Errorf("suggestions for @BX = %v, want @BA first", unresolved[0].Suggestions)
+	}
+	if len(unresolved[1].Suggestions) == 0 || unresolved[1].Suggestions[0].Code != "@@BAW" {
+		t.Errorf("suggestions for @@BAX = %v, want @@BAW", unresolved[1].Suggestions)
+	}
+}
+
+func TestLoadAirlines(t *testing.T) {
+	defer func() { diagnostics = nil }()
+	if err := loadAirlines(embeddedAirlinesPath); err != nil || len(airlines) == 0 {
+		t.Fatalf("embedded airlines: %v, %d loaded", err, len(airlines))
+	}
+	for _, airline := range airlines {
+		if !validString(airline.Name) {
+			t.Errorf("airline %v has an invalid name %q", airline.IATA_Code, airline.Name)
+		}
+	}
+
+	diagnostics = nil
+	if err := loadAirlines(testAirlines); err != nil || len(airlines) != 3 || len(diagnostics) != 1 {
+		t.Errorf("loadAirlines(%v) = %v with %d airlines and %d warnings, want 3 and the row without a name", testAirlines, err, len(airlines), len(diagnostics))
+	}
+	if err := loadAirlines("testdata/lookups/missing.csv"); !errors.Is(err, errLookupNotFound) {
+		t.Errorf("missing airline lookup = %v, want %v", err, errLookupNotFound)
+	}
+	if err := loadAirlines("testdata/lookups/missing-column.csv"); !errors.Is(err, errLookupMalformed) {
+		t.Errorf("airline lookup without iata_code = %v, want %v", err, errLookupMalformed)
+	}
+}
+
+func TestAirlineDuplicates(t *testing.T) {
+	defer func() {
+		duplicatePolicy = "first"
+		diagnostics = nil
+	}()
+	const conflict = "testdata/lookups/airlines-conflict.csv"
+
+	//The copied row is merged, Brit Air takes BA from British Airways
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{"first", "British Airways"},
+		{"last", "Brit Air"},
+	}
+	for _, test := range tests {
+		duplicatePolicy = test.policy
+		diagnostics = nil
+		if err := loadAirlines(conflict); err != nil {
+			t.Fatalf("--duplicates %v: %v", test.policy, err)
+		}
+		airline, _, _ := findAirlineByToken("@BA283")
+		if len(airlines) != 1 || airline.Name != test.want || len(diagnostics) != 1 || diagnostics[0].Code != diagLookupDuplicate {
+			t.Errorf("--duplicates %v: %d airlines, @BA is %q, diagnostics %v, want only %v", test.policy, len(airlines), airline.Name, diagnostics, test.want)
+			continue
+		}
+		//Both codes of the copy and BA of Brit Air
+		warning := diagnostics[0]
+		want := "3 airline codes are used by more than one row, kept the " + test.policy + " one"
+		if warning.Severity != severityWarning || warning.File != conflict || warning.Message != want {
+			t.Errorf("--duplicates %v warned %v %v: %q, want a warning for %v: %q", test.policy, warning.Severity, warning.File, warning.Message, conflict, want)
+		}
+	}
+
+	duplicatePolicy = "error"
+	if err := loadAirlines(conflict); !errors.Is(err, errLookupMalformed) {
+		t.Errorf("--duplicates error = %v, want %v", err, errLookupMalformed)
+	}
+}
+
+func TestAirlineRowReasons(t *testing.T) {
+	columns := map[string]int{"name": 0, "iata_code": 1, "icao_code": 2, "iso_country": 3}
+	tests := []struct {
+		fields []string
+		width  int
+		reason string
+	}{
+		{[]string{"Finnair", "AY", "FIN", "FI"}, 0, ""},
+		{[]string{"Finnair", "AY", "FIN"}, 4, reasonColumns},
+		{[]string{" ", "AY", "FIN", "FI"}, 0, reasonBlank},
+		{[]string{"Fin\xffair", "AY", "FIN", "FI"}, 0, reasonEncoding},
+		{[]string{"Finnair", "AYX", "FIN", "FI"}, 0, reasonCodeShape},
+		{[]string{"Finnair", "AY", "FI1", "FI"}, 0, reasonCodeShape},
+	}
+	for _, test := range tests {
+		_, problem := parseAirlineRow(lookupRecord{line: 2, fields: test.fields, width: test.width}, columns)
+		reason := ""
+		if problem != nil {
+			reason = problem.reason
+		}
+		if reason != test.reason {
+			t.Errorf("row %q skipped for %q, want %q", test.fields, reason, test.reason)
+		}
+	}
+
+	//The html links to the website, so it has to be a web address
+	columns["website"] = 4
+	for website, want := range map[string]string{
+		"":                            "",
+		"https://www.finnair.com":     "",
+		"http://www.finnair.com/fi":   "",
+		"javascript:alert(1)":         reasonWebsite,
+		"www.finnair.com":             reasonWebsite,
+		"ftp://www.finnair.com":       reasonWebsite,
+		"https://":                    reasonWebsite,
+		"data:text/html,<b>hello</b>": reasonWebsite,
+	} {
+		_, problem := parseAirlineRow(lookupRecord{line: 2, fields: []string{"Finnair", "AY", "FIN", "FI", website}}, columns)
+		reason := ""
+		if problem != nil {
+			reason = problem.reason
+		}
+		if reason != want {
+			t.Errorf("website %q skipped for %q, want %q", website, reason, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Points for how well a word of the query matches a field
+const (
+	scoreCode      = 100 //The whole IATA or ICAO code
+	scorePrefix    = 60  //The start of the name or city
+	scoreWord      = 40  //The start of another word of the name or city
+	scoreSubstring = 20  //Anywhere in the name or city
+	scoreCountry   = 10  //The country, by name or ISO code
+)
+
+type searchResult struct {
+	airport  Airport
+	score    int
+	distance float64
+}
+
+// Lower case without accents, so "zurich" finds Zürich
+func foldText(input string) string {
+	return strings.ToLower(transliterate(input))
+}
+
+func scoreText(field, word string) int {
+	switch {
+	case strings.HasPrefix(field, word):
+		return scorePrefix
+	case strings.Contains(" "+field, " "+word) || strings.Contains("-"+field, "-"+word):
+		return scoreWord
+	case strings.Contains(field, word):
+		return scoreSubstring
+	}
+	return 0
+}
+
+// Every word of the query has to match a field, the best field of each word counts
+func scoreAirport(airport Airport, words []string) int {
+	name := foldText(airport.Name)
+	city := foldText(airport.Municipality)
+	country := foldText(countryName(airport.ISO_Country))
+
+	total := 0
+	for _, word := range words {
+		best := 0
+		if word == strings.ToLower(airport.IATA_Code) || word == strings.ToLower(airport.ICAO_Code) {
+			best = scoreCode
+		}
+		best = max(best, scoreText(name, word), scoreText(city, word))
+		if word == strings.ToLower(airport.ISO_Country) || strings.HasPrefix(country, word) {
+			best = max(best, scoreCountry)
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total
+}
+
+// Reads a radius like 100km, 50mi or 20nm into kilometres and its unit
+func parseRadius(radius string) (float64, string, error) {
+	unit := "km"
+	for candidate := range distanceUnits {
+		if strings.HasSuffix(radius, candidate) {
+			unit = candidate
+			radius = strings.TrimSuffix(radius, candidate)
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(radius), 64)
+	if err != nil || value <= 0 {
+		return 0, "", fmt.Errorf("invalid radius, expected a distance like 100km, 50mi or 20nm")
+	}
+	return value * distanceUnits[unit], unit, nil
+}
+
+func searchAirports(query string, near bool, lat, lon, radiusKm float64) []searchResult {
+	words := strings.Fields(foldText(query))
+
+	var results []searchResult
+	for _, airport := range airports {
+		result := searchResult{airport: airport}
+		if len(words) > 0 {
+			result.score = scoreAirport(airport, words)
+			if result.score == 0 {
+				continue
+			}
+		}
+		if near {
+			result.distance = greatCircleDistance(lat, lon, airport.Latitude, airport.Longitude)
+			if result.distance > radiusKm {
+				continue
+			}
+		}
+		results = append(results, result)
+	}
+
+	//Best match first, then the closest, then by name
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		if near && results[i].distance != results[j].distance {
+			return results[i].distance < results[j].distance
+		}
+		return results[i].airport.Name < results[j].airport.Name
+	})
+	return results
+}
+
+func printSearchResults(results []searchResult, near bool) {
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "IATA\tICAO\tName\tCity\tCountry"
+	if near {
+		header += "\tDistance"
+	}
+	fmt.Fprintln(table, header)
+	for _, result := range results {
+		a := result.airport
+		row := fmt.Sprintf("%v\t%v\t%v\t%v\t%v", a.IATA_Code, a.ICAO_Code, a.Name, a.Municipality, countryName(a.ISO_Country))
+		if near {
+			row += "\t" + formatDistance(result.distance)
+		}
+		fmt.Fprintln(table, row)
+	}
+	table.Flush()
+}
+
+type searchOptions struct {
+	near   *string
+	radius *string
+	limit  *int
+}
+
+func registerSearchFlags(flags *flag.FlagSet) searchOptions {
+	var opts searchOptions
+	opts.near = flags.String("near", "", "Only airports around this point, as lat,lon")
+	opts.radius = flags.String("radius", "100km", "Distance from --near, in km, mi or nm")
+	opts.limit = flags.Int("limit", 20, "Most matches to print, 0 for all")
+	addLookupFlags(flags)
+	return opts
+}
+
+func runAirportsSearch(args []string) int {
+	searchFlags := newFlagSet("airports search")
+	opts := registerSearchFlags(searchFlags)
+	if err := parseFlags(searchFlags, args); err != nil {
+		return usageExit(err)
+	}
+	nearFlag, radiusFlag, limit := opts.near, opts.radius, opts.limit
+	query := strings.Join(searchFlags.Args(), " ")
+	if strings.TrimSpace(query) == "" && *nearFlag == "" {
+		searchFlags.Usage()
+		return exitUsage
+	}
+
+	defer flushDiagnostics()
+
+	if err := checkLookupFlags(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return exitUsage
+	}
+
+	var lat, lon, radiusKm float64
+	near := *nearFlag != ""
+	if near {
+		var err error
+		if lat, lon, err = parseCoordinates(*nearFlag, "latlon"); err != nil {
+			reportf(severityError, diagUsage, "invalid --near %q: %v", *nearFlag, err)
+			return exitUsage
+		}
+		radius, unit, err := parseRadius(*radiusFlag)
+		if err != nil {
+			reportf(severityError, diagUsage, "%v", err)
+			return exitUsage
+		}
+		radiusKm = radius
+		distanceUnit = unit
+	}
+
+	if err := loadAirports(chooseLookups(nil)...); err != nil {
+		return reportLookupError("", err)
+	}
+
+	//Lookup warnings would bury the matches, lookup validate lists them
+	diagnostics = nil
+
+	results := searchAirports(query, near, lat, lon, radiusKm)
+	if len(results) == 0 {
+		fmt.Println("No airports found")
+		return exitProblems
+	}
+	total := len(results)
+	if *limit > 0 && total > *limit {
+		results = results[:*limit]
+	}
+	printSearchResults(results, near)
+	if len(results) < total {
+		fmt.Printf("%s%d of %d matches, see --limit%s\n", Yellow, len(results), total, Reset)
+	}
+	return exitOK
+}
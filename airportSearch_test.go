@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFoldText(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Zürich", "zurich"},
+		{"São Paulo", "sao paulo"},
+		{"Reykjavík", "reykjavik"},
+		{"Málaga–Costa del Sol", "malaga-costa del sol"},
+		{"HEATHROW", "heathrow"},
+	}
+	for _, test := range tests {
+		if got := foldText(test.input); got != test.want {
+			t.Errorf("foldText(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	defer func() { airports = nil }()
+	airports = []Airport{
+		{Name: "Heathrow Heliport", Municipality: "Hayes", ISO_Country: "GB", IATA_Code: "HHX", ICAO_Code: "EGHX"},
+		{Name: "London Heathrow Airport", Municipality: "London", ISO_Country: "GB", IATA_Code: "LHR", ICAO_Code: "EGLL"},
+		{Name: "Sheathrow Field", Municipality: "Leeds", ISO_Country: "GB", IATA_Code: "SHX", ICAO_Code: "EGSX"},
+		{Name: "Zürich Airport", Municipality: "Zurich", ISO_Country: "CH", IATA_Code: "ZRH", ICAO_Code: "LSZH"},
+		{Name: "Lhasa Gonggar Airport", Municipality: "Lhasa", ISO_Country: "CN", IATA_Code: "LXA", ICAO_Code: "ZULS"},
+	}
+	tests := []struct {
+		query string
+		want  string //IATA codes, best first
+	}{
+		//The start of a name beats the start of a later word, which beats the middle of one
+		{"heathrow", "HHX LHR SHX"},
+		{"lhr", "LHR"},
+		{"egll", "LHR"},
+		{"zurich", "ZRH"},
+		{"ZÜRICH", "ZRH"},
+		//Every word has to match
+		{"heathrow london", "LHR"},
+		{"heathrow paris", ""},
+		{"switzerland", "ZRH"},
+		{"cn", "LXA"},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, result := range searchAirports(test.query, false, 0, 0, 0) {
+			got = append(got, result.airport.IATA_Code)
+		}
+		if strings.Join(got, " ") != test.want {
+			t.Errorf("search %q = %q, want %q", test.query, strings.Join(got, " "), test.want)
+		}
+	}
+}
+
+func TestSearchNear(t *testing.T) {
+	defer func() { airports = nil }()
+	airports = []Airport{
+		{Name: "Gatwick Airport", IATA_Code: "LGW", Latitude: 51.148, Longitude: -0.190},
+		{Name: "London Heathrow Airport", IATA_Code: "LHR", Latitude: 51.471, Longitude: -0.462},
+		{Name: "Zürich Airport", IATA_Code: "ZRH", Latitude: 47.465, Longitude: 8.549},
+	}
+	var got []string
+	for _, result := range searchAirports("", true, 51.47, -0.46, 100) {
+		got = append(got, result.airport.IATA_Code)
+	}
+	if strings.Join(got, " ") != "LHR LGW" {
+		t.Errorf("airports within 100 km of Heathrow = %v, want LHR LGW closest first", got)
+	}
+}
+
+func TestParseRadius(t *testing.T) {
+	tests := []struct {
+		radius string
+		km     float64
+		unit   string
+	}{
+		{"100km", 100, "km"},
+		{"100", 100, "km"},
+		{"50mi", 80.4672, "mi"},
+		{"20nm", 37.04, "nm"},
+		{" 2.5 km", 2.5, "km"},
+	}
+	for _, test := range tests {
+		km, unit, err := parseRadius(test.radius)
+		if err != nil {
+			t.Errorf("parseRadius(%q) = %v", test.radius, err)
+			continue
+		}
+		if math.Abs(km-test.km) > 1e-9 || unit != test.unit {
+			t.Errorf("parseRadius(%q) = %v %v, want %v %v", test.radius, km, unit, test.km, test.unit)
+		}
+	}
+	for _, radius := range []string{"", "km", "far", "-5km", "0mi", "10yd"} {
+		if _, _, err := parseRadius(radius); err == nil {
+			t.Errorf("parseRadius(%q) gave no error", radius)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Set with --backup, keeps the version an output replaces
+var backupMode = ""
+
+var backupModes = map[string]bool{
+	"":        true,
+	"bak":     true,
+	"history": true,
+}
+
+// Folder next to the output that keeps every replaced version
+const historyDir = ".history"
+
+// Writes content to a temporary file next to path and renames it into place,
+// so a failed write leaves the previous file as it was
+func writeFileAtomic(path string, content []byte) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	temp, err := os.CreateTemp(dir, "."+name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	//Only left behind when something failed
+	defer os.Remove(temp.Name())
+
+	//Keep the permissions of the file that is replaced
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Copies the current version of path aside before it is replaced, returns where it went
+func backupFile(path string, mode string, now time.Time) (string, error) {
+	previous, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	backup := path + ".bak"
+	if mode == "history" {
+		dir, name := filepath.Split(path)
+		ext := filepath.Ext(name)
+		if err := os.MkdirAll(filepath.Join(dir, historyDir), 0755); err != nil {
+			return "", err
+		}
+		backup = filepath.Join(dir, historyDir, strings.TrimSuffix(name, ext)+"-"+now.Format("20060102-150405")+ext)
+		if _, err := os.Stat(backup); err == nil {
+			if backup, err = nextFreeName(backup); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := writeFileAtomic(backup, previous); err != nil {
+		return "", fmt.Errorf("error writing backup %v: %w", backup, err)
+	}
+	return backup, nil
+}
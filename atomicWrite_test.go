@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Names in dir that look like the temporary files of writeFileAtomic
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			found = append(found, entry.Name())
+		}
+	}
+	return found
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(content) != "new" || info.Mode().Perm() != 0600 {
+		t.Errorf("got %q with mode %v, want %q with the old mode 0600", content, info.Mode().Perm(), "new")
+	}
+	if found := tempFiles(t, dir); len(found) > 0 {
+		t.Errorf("temporary files left behind: %v", found)
+	}
+}
+
+func TestWriteFileAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	//A folder that isn't empty can't be renamed over, so the write fails at the very end
+	path := filepath.Join(dir, "out.txt")
+	inside := filepath.Join(path, "keep.txt")
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(inside, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("new")); err == nil {
+		t.Fatal("writing over a folder gave no error")
+	}
+	if content, err := os.ReadFile(inside); err != nil || string(content) != "old" {
+		t.Errorf("the original was changed: %q, %v", content, err)
+	}
+	if found := tempFiles(t, dir); len(found) > 0 {
+		t.Errorf("temporary files left behind: %v", found)
+	}
+
+	//A missing folder fails before anything is written
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "out.txt"), []byte("new")); err == nil {
+		t.Error("writing into a missing folder gave no error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("the missing folder was created: %v", err)
+	}
+}
+
+func TestBackupFile(t *testing.T) {
+	now := time.Date(2024, 1, 31, 15, 45, 0, 0, time.UTC)
+
+	t.Run("nothing to back up", func(t *testing.T) {
+		backup, err := backupFile(filepath.Join(t.TempDir(), "out.txt"), "bak", now)
+		if backup != "" || err != nil {
+			t.Errorf("got %q, %v, want no backup", backup, err)
+		}
+	})
+
+	t.Run("bak", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out.txt")
+		for _, version := range []string{"first", "second"} {
+			if err := os.WriteFile(path, []byte(version), 0644); err != nil {
+				t.Fatal(err)
+			}
+			backup, err := backupFile(path, "bak", now)
+			if err != nil || backup != path+".bak" {
+				t.Fatalf("got %q, %v, want %q", backup, err, path+".bak")
+			}
+			//Only the last version is kept
+			if content, _ := os.ReadFile(backup); string(content) != version {
+				t.Errorf("backup holds %q, want %q", content, version)
+			}
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "out.txt")
+		want := []string{
+			filepath.Join(dir, historyDir, "out-20240131-154500.txt"),
+			filepath.Join(dir, historyDir, "out-20240131-154500 (1).txt"),
+		}
+		for i, version := range []string{"first", "second"} {
+			if err := os.WriteFile(path, []byte(version), 0644); err != nil {
+				t.Fatal(err)
+			}
+			backup, err := backupFile(path, "history", now)
+			if err != nil || backup != want[i] {
+				t.Fatalf("got %q, %v, want %q", backup, err, want[i])
+			}
+			if content, _ := os.ReadFile(backup); string(content) != version {
+				t.Errorf("%v holds %q, want %q", backup, content, version)
+			}
+		}
+	})
+}
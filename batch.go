@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type batchOptions struct {
+	convertOptions
+	outDir *string
+	format *string
+}
+
+func registerBatchFlags(flags *flag.FlagSet) batchOptions {
+	opts := batchOptions{convertOptions: registerConvertFlags(flags)}
+	opts.outDir = flags.String("out-dir", ".", "Folder the outputs are written to")
+	opts.format = flags.String("format", "txt", "Output format of every itinerary: txt, html, json or ics")
+	return opts
+}
+
+// Output of an input in the batch folder, "trips/rome.txt" becomes "out/rome.html"
+func batchOutputPath(inputPath, outDir, format string) string {
+	name := filepath.Base(inputPath)
+	return filepath.Join(outDir, strings.TrimSuffix(name, filepath.Ext(name))+"."+format)
+}
+
+func runBatch(args []string) int {
+	batchFlags := newFlagSet("batch")
+	opts := registerBatchFlags(batchFlags)
+	if err := parseFlags(batchFlags, args); err != nil {
+		return usageExit(err)
+	}
+	if len(batchFlags.Args()) == 0 {
+		batchFlags.Usage()
+		return exitUsage
+	}
+
+	defer flushDiagnostics()
+
+	policy, exit := checkConvertOptions(opts.convertOptions)
+	if exit != exitOK {
+		return exit
+	}
+	if !containsWord(outputFormats, *opts.format) {
+		reportf(severityError, diagUsage, "unknown output format %v, expected %v", *opts.format, strings.Join(outputFormats, ", "))
+		return exitUsage
+	}
+
+	if err := os.MkdirAll(*opts.outDir, 0755); err != nil {
+		report(Diagnostic{Code: diagOutputFailed, Severity: severityError, File: *opts.outDir, Message: "error creating folder: " + err.Error()})
+		return exitOutput
+	}
+
+	//Every itinerary shares one lookup, given with --lookup or the config
+	if err := loadAirports(chooseLookups(nil)...); err != nil {
+		return reportLookupError("", err)
+	}
+	if err := loadAirlines(chooseAirlines()); err != nil {
+		return reportLookupError("", err)
+	}
+
+	//One failed itinerary doesn't stop the others, the first failure is the exit code
+	result := exitOK
+	converted := 0
+	inputs := batchFlags.Args()
+	for _, inputPath := range inputs {
+		exit := convertFile(inputPath, batchOutputPath(inputPath, *opts.outDir, *opts.format), policy, *opts.strict)
+		if exit == exitOK {
+			converted++
+		} else if result == exitOK {
+			result = exit
+		}
+	}
+
+	if diagnosticsFormat == "text" {
+		fmt.Printf("%s%d of %d itineraries converted%s\n", Blue, converted, len(inputs), Reset)
+	}
+	return result
+}
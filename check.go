@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Anything that looks like a date/time token, valid or not
+var timeTokenCandidate = regexp.MustCompile(`(?:D|T12|T24)\([^()\n]*\)`)
+
+func checkTimes(path string, lineNumber int, line string, previous *time.Time, previousToken *string) []Diagnostic {
+	var found []Diagnostic
+	problem := func(sev severity, code string, column int, message string) {
+		found = append(found, Diagnostic{Code: code, Severity: sev, File: path, Line: lineNumber, Column: column, Message: message})
+	}
+
+	for _, loc := range timeTokenCandidate.FindAllStringIndex(line, -1) {
+		start, end := loc[0], loc[1]
+		match := line[start:end]
+		column := len([]rune(line[:start])) + 1
+
+		//D( inside a word is not a token
+		if start > 0 && isAlphaNumeric(rune(line[start-1])) {
+			continue
+		}
+
+		if timeToken.FindString(match) != match {
+			problem(severityError, diagTokenMalformed, column, fmt.Sprintf("malformed date/time token %v, it will not be converted", match))
+			continue
+		}
+
+		instant, err := tokenInstant(match)
+		if err != nil {
+			problem(severityError, diagTokenInvalid, column, fmt.Sprintf("invalid date or time in %v", match))
+			continue
+		}
+		_, offset := instant.Zone()
+		if !validOffset(offset / 60) {
+			problem(severityError, diagOffsetInvalid, column, fmt.Sprintf("offset out of range in %v, it must be between -12:00 and +14:00", match))
+			continue
+		}
+
+		//Segments should follow each other in time
+		if !previous.IsZero() && instant.Before(*previous) {
+			problem(severityWarning, diagTimeOrder, column, fmt.Sprintf("%v is earlier than the previous time %v", match, *previousToken))
+		}
+		*previous = instant
+		*previousToken = match
+	}
+	return found
+}
+
+func checkGluedCodes(path string, lineNumber int, line string) []Diagnostic {
+	re := regexp.MustCompile(`##[A-Z]{4}|#[A-Z]{3}`)
+
+	var found []Diagnostic
+	for _, loc := range re.FindAllStringIndex(line, -1) {
+		start, end := loc[0], loc[1]
+
+		//A third # is a typo the converters ignore as well
+		if start > 0 && line[start-1] == '#' {
+			continue
+		}
+		if (start > 0 && isAlphaNumeric(rune(line[start-1]))) || (end < len(line) && isAlphaNumeric(rune(line[end]))) {
+			found = append(found, Diagnostic{Code: diagCodeGlued, Severity: severityWarning, File: path, Line: lineNumber,
+				Column:  len([]rune(line[:start])) + 1,
+				Message: fmt.Sprintf("airport code %v is next to other text and will not be converted", line[start:end])})
+		}
+	}
+	return found
+}
+
+func checkItinerary(path string, read Itinerary) []Diagnostic {
+	var found []Diagnostic
+	var previous time.Time
+	var previousToken string
+
+	//Tokens and codes are checked in the text as written, the segments of a booking by their times
+	read.eachParagraph(func(text string, line int, segment *Segment) {
+		if text == "" {
+			if segment.LocalOnly || segment.Departure.IsZero() {
+				return
+			}
+			if !previous.IsZero() && segment.Departure.Before(previous) {
+				d := Diagnostic{Code: diagTimeOrder, Severity: severityWarning, File: path,
+					Message: fmt.Sprintf("flight from %v departs before the previous time %v", segment.From, previousToken)}
+				if segment.Line > 0 {
+					d.Line, d.Column = segment.Line, 1
+				}
+				found = append(found, d)
+			}
+			previous, previousToken = segment.Departure, formatSegmentTime(segment.Departure, false)
+			if !segment.Arrival.IsZero() {
+				previous, previousToken = segment.Arrival, formatSegmentTime(segment.Arrival, false)
+			}
+			return
+		}
+
+		for i, textLine := range strings.Split(text, "\n") {
+			lineNumber := 0
+			if line > 0 {
+				lineNumber = line + i
+			}
+			found = append(found, checkTimes(path, lineNumber, textLine, &previous, &previousToken)...)
+			found = append(found, checkGluedCodes(path, lineNumber, textLine)...)
+		}
+	})
+
+	//Unknown codes come with the same suggestions as the conversion report
+	for _, code := range findUnresolvedInItinerary(read) {
+		found = append(found, Diagnostic{Code: diagCodeUnresolved, Severity: severityError, File: path, Line: code.Line, Column: code.Column,
+			Message: "unknown " + code.Kind + " code " + code.Code, Suggestions: code.Suggestions})
+	}
+	found = append(found, validateItinerary(path, read)...)
+
+	sortByPosition(found)
+	return found
+}
+
+// Orders diagnostics of one file by line and column, those without a line first
+func sortByPosition(found []Diagnostic) {
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Line != found[j].Line {
+			return found[i].Line < found[j].Line
+		}
+		return found[i].Column < found[j].Column
+	})
+}
+
+func registerCheckFlags(flags *flag.FlagSet) {
+	addLookupFlags(flags)
+	flags.StringVar(&diagnosticsFormat, "diagnostics", "text", "Format of the problems: text or json")
+}
+
+func runCheck(args []string) int {
+	checkFlags := newFlagSet("check")
+	registerCheckFlags(checkFlags)
+	addAirlineFlags(checkFlags)
+	addInputFlags(checkFlags)
+	if err := parseFlags(checkFlags, args); err != nil {
+		return usageExit(err)
+	}
+	if len(checkFlags.Args()) < 1 {
+		checkFlags.Usage()
+		return exitUsage
+	}
+
+	//Problems are the output of check, so they go to stdout
+	defer renderDiagnostics(os.Stdout, diagnosticsFormat, isTerminal(os.Stdout))
+
+	if err := checkDiagnosticsFormat(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return exitUsage
+	}
+	if err := checkLookupFlags(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return exitUsage
+	}
+	if err := checkInputFormat(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return exitUsage
+	}
+
+	inputPath := checkFlags.Args()[0]
+	lookupPaths := chooseLookups(checkFlags.Args()[1:])
+
+	userInput, err := loadFile(inputPath)
+	if err != nil {
+		report(Diagnostic{Code: diagInputMissing, Severity: severityError, File: inputPath, Message: "input not found: " + err.Error()})
+		return exitInput
+	}
+	if err := loadAirports(lookupPaths...); err != nil {
+		return reportLookupError("", err)
+	}
+	if err := loadAirlines(chooseAirlines()); err != nil {
+		return reportLookupError("", err)
+	}
+
+	//Lookup warnings are not problems of the itinerary
+	lookupWarnings := len(diagnostics)
+
+	//A PNR or JSON booking is checked on its segments, at their lines
+	itinerary, err := parseItinerary(inputPath, userInput, time.Now())
+	if err != nil {
+		report(Diagnostic{Code: diagInputMalformed, Severity: severityError, File: inputPath, Message: err.Error()})
+		return exitInput
+	}
+	//Warnings of reading the booking are sorted in with the problems found on its lines
+	found := append([]Diagnostic{}, diagnostics[lookupWarnings:]...)
+	found = append(found, checkItinerary(inputPath, itinerary)...)
+	sortByPosition(found)
+	diagnostics = append(diagnostics[:lookupWarnings], found...)
+	if len(diagnostics) > lookupWarnings {
+		return exitProblems
+	}
+	return exitOK
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Runs a command with its stdout captured, check prints the problems there
+func runCaptured(t *testing.T, args []string) (int, string) {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	exit := runCommand(args)
+	os.Stdout = stdout
+	writer.Close()
+	output, _ := io.ReadAll(reader)
+	reader.Close()
+	diagnostics = nil
+	return exit, string(output)
+}
+
+func TestRunCheck(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	clean := write("clean.txt", "Flight from #LHR to #LAX\nDeparts T24(2023-06-15T14:00+01:00)\n")
+	problems := write("problems.txt", "Flight from #LHR to #LZX\nDeparts T24(2023-06-15T14:00+01:00)\nBack T24(2023-06-14T14:00+01:00)\n")
+	//The unreadable segment on line 5 is found while reading, the unknown code on line 4 while checking
+	booking := write("trip.pnr", "RP/LONBA0100\n  1.1SMITH/JOHN MR\n  2  BA 283 Y 15JUN 4 LAXLHR HK2  1400 0830+1\n"+
+		"  3  BA 284 Y 16JUN 5 LHRZZZ HK2  1015 1325\n  4  ZZ 9 Y 03JAN LHRLAX\n")
+	lookup := []string{"--lookup", goldenLookup, "--airlines", testAirlines}
+	defer func() { lookupFlags, airlinesPath, diagnosticsFormat = nil, "", "text" }()
+
+	tests := []struct {
+		args []string
+		exit int
+		want []string //the problems in the order they are printed
+	}{
+		{append([]string{"check"}, append(lookup, clean)...), exitOK, nil},
+		{append([]string{"check"}, append(lookup, problems)...), exitProblems, []string{
+			problems + ":1:21: error: unknown airport code #LZX",
+			problems + ":3:6: warning: T24(2023-06-14T14:00+01:00) is earlier than the previous time",
+		}},
+		{append([]string{"check"}, append(lookup, booking)...), exitProblems, []string{
+			booking + ":4:1: error: unknown airport code #ZZZ",
+			booking + ":5:1: warning: unreadable PNR segment",
+		}},
+		{append([]string{"check"}, append(lookup, filepath.Join(dir, "missing.txt"))...), exitInput, []string{
+			"input not found",
+		}},
+		{[]string{"check", "--lookup", filepath.Join(dir, "missing.csv"), clean}, exitLookupNotFound, []string{
+			"airport lookup not found",
+		}},
+		{append([]string{"check", "--diagnostics", "xml"}, append(lookup, clean)...), exitUsage, []string{
+			"unknown diagnostics format xml",
+		}},
+		{[]string{"check"}, exitUsage, nil},
+	}
+	for _, test := range tests {
+		lookupFlags = nil
+		exit, output := runCaptured(t, test.args)
+		if exit != test.exit {
+			t.Errorf("%q exited with %d, want %d", test.args, exit, test.exit)
+		}
+
+		//Warnings about the test lookups are not what is checked here
+		var lines []string
+		for _, line := range strings.Split(output, "\n") {
+			if line != "" && !strings.HasPrefix(line, "testdata") {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) != len(test.want) {
+			t.Errorf("%q printed\n%v\nwant %d problems", test.args, output, len(test.want))
+			continue
+		}
+		for i, want := range test.want {
+			if !strings.Contains(lines[i], want) {
+				t.Errorf("%q problem %d = %q, want %q", test.args, i+1, lines[i], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errCollisionCancelled = errors.New("cancelled by the user")
+	errOutputExists       = errors.New("output already exists")
+	errNobodyToAsk        = fmt.Errorf("%w and stdin is not a terminal to ask, choose what to do with -o, -r or --on-collision", errOutputExists)
+)
+
+// Decides where to write when the output already exists
+type CollisionPolicy interface {
+	//Returns the path to write instead of path, which exists
+	Resolve(path string) (string, error)
+}
+
+// Names accepted by --on-collision
+var collisionPolicyNames = []string{"prompt", "overwrite", "rename", "timestamp", "fail"}
+
+func newCollisionPolicy(name string) (CollisionPolicy, error) {
+	switch name {
+	case "prompt":
+		return promptPolicy{in: os.Stdin, out: os.Stdout, interactive: isTerminal(os.Stdin)}, nil
+	case "overwrite":
+		return overwritePolicy{}, nil
+	case "rename":
+		return renamePolicy{}, nil
+	case "timestamp":
+		return timestampPolicy{now: time.Now}, nil
+	case "fail":
+		return failPolicy{}, nil
+	}
+	return nil, fmt.Errorf("unknown collision policy %v, expected one of %v", name, strings.Join(collisionPolicyNames, ", "))
+}
+
+// Picks the path to write the output to, reporting what was done about an existing file
+func resolveOutputPath(policy CollisionPolicy, path string) (string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path, nil
+	} else if err != nil {
+		//Writing will fail with the actual reason
+		report(Diagnostic{Code: diagFileCollision, Severity: severityWarning, File: path,
+			Message: "could not access file, option to overwrite is unavailable: " + err.Error()})
+		return path, nil
+	}
+
+	resolved, err := policy.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved == path {
+		report(Diagnostic{Code: diagFileCollision, Severity: severityInfo, File: path, Message: "output already exists and is overwritten"})
+	} else {
+		report(Diagnostic{Code: diagFileCollision, Severity: severityInfo, File: path, Message: "output already exists, writing " + resolved + " instead"})
+	}
+	return resolved, nil
+}
+
+// First free "name (n).ext" next to path, for any extension
+func nextFreeName(path string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 1; ; n++ {
+		candidate := base + " (" + strconv.Itoa(n) + ")" + ext
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		} else if err != nil {
+			return "", fmt.Errorf("could not access %v: %w", candidate, err)
+		}
+	}
+}
+
+type overwritePolicy struct{}
+
+func (overwritePolicy) Resolve(path string) (string, error) {
+	return path, nil
+}
+
+type renamePolicy struct{}
+
+func (renamePolicy) Resolve(path string) (string, error) {
+	return nextFreeName(path)
+}
+
+// Appends the time of the run, "output-20240131-154500.txt"
+type timestampPolicy struct {
+	now func() time.Time
+}
+
+func (t timestampPolicy) Resolve(path string) (string, error) {
+	ext := filepath.Ext(path)
+	stamped := strings.TrimSuffix(path, ext) + "-" + t.now().Format("20060102-150405") + ext
+	if _, err := os.Stat(stamped); os.IsNotExist(err) {
+		return stamped, nil
+	}
+	//Two runs within the same second
+	return nextFreeName(stamped)
+}
+
+type failPolicy struct{}
+
+func (failPolicy) Resolve(path string) (string, error) {
+	return "", fmt.Errorf("%w, choose what to do with -o, -r or --on-collision", errOutputExists)
+}
+
+// Asks the user, unless nobody is there to answer
+type promptPolicy struct {
+	in          io.Reader
+	out         io.Writer
+	interactive bool
+}
+
+func (p promptPolicy) Resolve(path string) (string, error) {
+	if !p.interactive {
+		return "", errNobodyToAsk
+	}
+	renamed, err := nextFreeName(path)
+	if err != nil {
+		return "", err
+	}
+
+	//Prompt the user to choose to Overwrite / Keep / Cancel
+	scanner := bufio.NewScanner(p.in)
+	for {
+		fmt.Fprintf(p.out, "\n%s%v already exists%s\n\n", Red, path, Reset)
+		fmt.Fprintln(p.out, "Choose an option:")
+		fmt.Fprintln(p.out, "1 - Overwrite")
+		fmt.Fprintf(p.out, "2 - Change Name to:%v\n", renamed)
+		fmt.Fprintln(p.out, "3 - Cancel")
+
+		//Closed input such as /dev/null means nobody is going to choose
+		if !scanner.Scan() {
+			return "", errNobodyToAsk
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil || choice < 1 || choice > 3 {
+			fmt.Fprintln(p.out, "Invalid choice. Please enter 1, 2, or 3.")
+			continue
+		}
+
+		fmt.Fprintln(p.out, "You chose to:", options[choice])
+		switch choice {
+		case 1:
+			return path, nil
+		case 2:
+			return renamed, nil
+		}
+		return "", errCollisionCancelled
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func touch(t *testing.T, path string) {
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenamePastNinetyNine(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"out.tar.gz", "README", "page.html"} {
+		path := filepath.Join(dir, name)
+		touch(t, path)
+		ext := filepath.Ext(name)
+		for n := 1; n <= 120; n++ {
+			touch(t, filepath.Join(dir, fmt.Sprintf("%v (%d)%v", strings.TrimSuffix(name, ext), n, ext)))
+		}
+
+		got, err := renamePolicy{}.Resolve(path)
+		want := filepath.Join(dir, strings.TrimSuffix(name, ext)+" (121)"+ext)
+		if err != nil || got != want {
+			t.Errorf("%v: got %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestTimestampPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	touch(t, path)
+	policy := timestampPolicy{now: func() time.Time { return time.Date(2024, 1, 31, 15, 45, 0, 0, time.UTC) }}
+
+	first, _ := policy.Resolve(path)
+	touch(t, first)
+	second, _ := policy.Resolve(path)
+	if filepath.Base(first) != "out-20240131-154500.txt" || filepath.Base(second) != "out-20240131-154500 (1).txt" {
+		t.Errorf("got %q and %q", first, second)
+	}
+}
+
+func TestPromptPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	touch(t, path)
+
+	tests := []struct {
+		answers string
+		want    string
+		err     error
+	}{
+		{"1\n", path, nil},
+		{"x\n2\n", strings.TrimSuffix(path, ".txt") + " (1).txt", nil},
+		{"3\n", "", errCollisionCancelled},
+		{"", "", errOutputExists},
+	}
+	for _, test := range tests {
+		policy := promptPolicy{in: strings.NewReader(test.answers), out: io.Discard, interactive: true}
+		got, err := policy.Resolve(path)
+		if got != test.want || !errors.Is(err, test.err) {
+			t.Errorf("answers %q: got %q, %v, want %q, %v", test.answers, got, err, test.want, test.err)
+		}
+	}
+
+	//Nobody is asked when stdin is not a terminal
+	policy := promptPolicy{in: strings.NewReader("1\n"), out: io.Discard, interactive: false}
+	if _, err := policy.Resolve(path); !errors.Is(err, errOutputExists) {
+		t.Errorf("non-interactive: got %v, want %v", err, errOutputExists)
+	}
+}
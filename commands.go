@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// Name of the installed binary, used in completion scripts
+const programName = "itinerary"
+
+// Set when building a release with -ldflags "-X main.version=v1.2.0"
+var version = "dev"
+
+type command struct {
+	name    string                    //Words that pick the command, "lookup validate"
+	args    string                    //Positional arguments for the usage line
+	summary string                    //One line for the command list
+	flags   func(flags *flag.FlagSet) //Registers the flags, for help and completion
+	run     func(args []string) int
+}
+
+// Every command in the order of the help, filled in init because completion refers to it
+var commands []command
+
+func init() {
+	commands = []command{
+		{"convert", "./input.txt ./output.txt [./airport-lookup.csv ./overrides.csv ...]",
+			"Prettify an itinerary into text, html, json or ics, the default command",
+			func(f *flag.FlagSet) { registerConvertFlags(f) }, runConvert},
+		{"check", "./input.txt [./airport-lookup.csv ./overrides.csv ...]",
+			"Report problems without writing output",
+			func(f *flag.FlagSet) { registerCheckFlags(f); addAirlineFlags(f); addInputFlags(f) }, runCheck},
+		{"batch", "./input.txt [./input2.txt ...]",
+			"Convert several itineraries into a folder",
+			func(f *flag.FlagSet) { registerBatchFlags(f) }, runBatch},
+		{"airports search", "<query>",
+			"Find airports by name, city, country or code",
+			func(f *flag.FlagSet) { registerSearchFlags(f) }, runAirportsSearch},
+		{"lookup validate", "[./airport-lookup.csv]",
+			"Report every unusable lookup row",
+			func(f *flag.FlagSet) { registerValidateFlags(f) }, runLookupValidate},
+		{"lookup info", "[./airport-lookup.csv]",
+			"Show the rows, date and checksum of a lookup, the embedded one by default",
+			addLookupFlags, runLookupInfo},
+		{"index build", "./airport-lookup.csv",
+			"Compile the lookup into airport-lookup.csv.idx for a faster start",
+			registerIndexFlags, runIndexBuild},
+		{"config show", "",
+			"Print every option with its value and where it came from",
+			func(f *flag.FlagSet) { registerConvertFlags(f) }, runConfigShow},
+		{"version", "",
+			"Print the version and build information",
+			nil, runVersion},
+		{"completion", "bash|zsh|fish",
+			"Print a shell completion script",
+			nil, runCompletion},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// Commands below a group such as lookup
+func subcommands(group string) []command {
+	var found []command
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd.name, group+" ") {
+			found = append(found, cmd)
+		}
+	}
+	return found
+}
+
+// A flag set whose -h prints the help of its command
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		printCommandHelp(name)
+	}
+	return flags
+}
+
+// -h is not a mistake
+func usageExit(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
+
+func flagName(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+func printCommandHelp(name string) {
+	cmd, found := findCommand(name)
+	if !found {
+		printHelp()
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%sUsage:%s go run . %v [flags] %v\n", Blue, Reset, cmd.name, cmd.args)
+	fmt.Fprintf(os.Stderr, "  %v\n", cmd.summary)
+	if cmd.flags == nil {
+		fmt.Fprintln(os.Stderr)
+		return
+	}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	cmd.flags(flags)
+	fmt.Fprintf(os.Stderr, "\n%sFlags:%s\n", Yellow, Reset)
+	flags.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(os.Stderr, "  %v %s- %v", flagName(f.Name), Yellow, f.Usage)
+		if f.DefValue != "" && f.DefValue != "false" {
+			fmt.Fprintf(os.Stderr, " (default %v)", f.DefValue)
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", Reset)
+	})
+	fmt.Fprintln(os.Stderr)
+}
+
+func printHelp() {
+	//Help is printed when there are no arguments or -h flag is used
+	fmt.Printf("\n%sItinerary usage:%s\n", Blue, Reset)
+	fmt.Printf("  go run . -h %s-- Show this help message%s\n", Red, Reset)
+	fmt.Printf("  go run . <command> -h %s-- Show the flags of a command%s\n", Red, Reset)
+	for _, cmd := range commands {
+		usage := cmd.name
+		if cmd.args != "" {
+			usage += " " + Yellow + cmd.args + Reset
+		}
+		fmt.Printf("  go run . %v %s-- %v%s\n", usage, Green, cmd.summary, Reset)
+	}
+	fmt.Println("  Without a command the arguments are converted, go run . ./input.txt ./output.txt works as before.")
+	fmt.Println("  Later lookup files override earlier ones by IATA and ICAO code.")
+	fmt.Println("  This program prettifies your itinerary that you input.")
+	fmt.Println("  Providing invalid input will result in an error message")
+	fmt.Printf("\n%sExit codes:%s\n", Yellow, Reset)
+	fmt.Println("  0 success, 1 problems found, 2 usage error, 3 input not found,")
+	fmt.Println("  4 airport lookup not found, 5 airport lookup malformed, 6 output not written")
+	println("")
+}
+
+// Picks the command from the first arguments, anything else is a conversion
+func runCommand(args []string) int {
+	if len(args) == 0 {
+		printHelp()
+		return exitOK
+	}
+	switch args[0] {
+	case "-h", "-help", "--help":
+		printHelp()
+		return exitOK
+	case "help":
+		if len(args) > 1 {
+			printCommandHelp(strings.Join(args[1:], " "))
+		} else {
+			printHelp()
+		}
+		return exitOK
+	case "-version", "--version":
+		return runVersion(nil)
+	}
+
+	if len(args) > 1 {
+		if cmd, found := findCommand(args[0] + " " + args[1]); found {
+			return cmd.run(args[2:])
+		}
+	}
+	if cmd, found := findCommand(args[0]); found {
+		return cmd.run(args[1:])
+	}
+	if group := subcommands(args[0]); len(group) > 0 {
+		fmt.Fprintf(os.Stderr, "%sUsage:%s\n", Blue, Reset)
+		for _, cmd := range group {
+			fmt.Fprintf(os.Stderr, "  go run . %v %v %s-- %v%s\n", cmd.name, cmd.args, Green, cmd.summary, Reset)
+		}
+		return exitUsage
+	}
+	return runConvert(args)
+}
+
+func runVersion(args []string) int {
+	fmt.Printf("%v %v\n", programName, buildVersion())
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return exitOK
+	}
+	fmt.Printf("  go:      %v\n", info.GoVersion)
+	settings := map[string]string{}
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+	if revision := settings["vcs.revision"]; revision != "" {
+		if settings["vcs.modified"] == "true" {
+			revision += " (modified)"
+		}
+		fmt.Printf("  commit:  %v\n", revision)
+		fmt.Printf("  built:   %v\n", settings["vcs.time"])
+	}
+	fmt.Printf("  lookup:  %v\n", embeddedLookupDateText())
+	return exitOK
+}
+
+func buildVersion() string {
+	//go install stamps the module version, release builds set it with -ldflags
+	if info, ok := debug.ReadBuildInfo(); ok && version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Shells completion scripts are generated for
+var completionShells = map[string]func() string{
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+// Flags of a command as typed on the command line, --coords or -o
+func commandFlags(cmd command) []string {
+	if cmd.flags == nil {
+		return nil
+	}
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	cmd.flags(flags)
+	var names []string
+	flags.VisitAll(func(f *flag.Flag) {
+		names = append(names, flagName(f.Name))
+	})
+	return names
+}
+
+// Words that may follow a command: the flags of a leaf, the subcommands of a group
+func completionWords() map[string][]string {
+	words := map[string][]string{"": {"help", "--help", "--version"}}
+	for _, cmd := range commands {
+		parts := strings.Fields(cmd.name)
+		for i := range parts {
+			parent := strings.Join(parts[:i], " ")
+			if !containsWord(words[parent], parts[i]) {
+				words[parent] = append(words[parent], parts[i])
+			}
+		}
+		words[cmd.name] = append(words[cmd.name], commandFlags(cmd)...)
+	}
+	words["completion"] = []string{"bash", "zsh", "fish"}
+	return words
+}
+
+func containsWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// Commands and groups in a stable order, groups before their commands
+func completionPaths() []string {
+	var paths []string
+	for _, cmd := range commands {
+		parts := strings.Fields(cmd.name)
+		for i := 1; i <= len(parts); i++ {
+			path := strings.Join(parts[:i], " ")
+			if !containsWord(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+func bashCompletion() string {
+	words := completionWords()
+	var script strings.Builder
+	fmt.Fprintf(&script, "# bash completion for %v, load with: source <(%v completion bash)\n", programName, programName)
+	fmt.Fprintf(&script, "_%v() {\n", programName)
+	script.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" cmd=\"\" i\n")
+	script.WriteString("    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	script.WriteString("        case \"$cmd ${COMP_WORDS[i]}\" in\n")
+	var known []string
+	for _, path := range completionPaths() {
+		known = append(known, fmt.Sprintf("%q", " "+path))
+	}
+	fmt.Fprintf(&script, "            %v) cmd=\"$cmd ${COMP_WORDS[i]}\" ;;\n", strings.Join(known, "|"))
+	script.WriteString("            *) break ;;\n")
+	script.WriteString("        esac\n")
+	script.WriteString("    done\n")
+	script.WriteString("    local words=\"\" leaf=0\n")
+	script.WriteString("    case \"$cmd\" in\n")
+	fmt.Fprintf(&script, "        \"\") words=%q ;;\n", strings.Join(words[""], " "))
+	for _, path := range completionPaths() {
+		leaf := 0
+		if _, found := findCommand(path); found && path != "completion" {
+			leaf = 1
+		}
+		fmt.Fprintf(&script, "        %q) words=%q leaf=%d ;;\n", " "+path, strings.Join(words[path], " "), leaf)
+	}
+	script.WriteString("    esac\n")
+	script.WriteString("    if [[ $leaf == 1 && $cur != -* ]]; then\n")
+	script.WriteString("        COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	script.WriteString("    else\n")
+	script.WriteString("        COMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	script.WriteString("    fi\n")
+	script.WriteString("}\n")
+	fmt.Fprintf(&script, "complete -o filenames -F _%v %v\n", programName, programName)
+	return script.String()
+}
+
+func zshCompletion() string {
+	words := completionWords()
+	var script strings.Builder
+	fmt.Fprintf(&script, "#compdef %v\n", programName)
+	fmt.Fprintf(&script, "# zsh completion for %v, load with: source <(%v completion zsh)\n", programName, programName)
+	fmt.Fprintf(&script, "_%v() {\n", programName)
+	script.WriteString("    local cmd=\"\" i\n")
+	script.WriteString("    for ((i = 2; i < CURRENT; i++)); do\n")
+	script.WriteString("        case \"$cmd ${words[i]}\" in\n")
+	var known []string
+	for _, path := range completionPaths() {
+		known = append(known, fmt.Sprintf("%q", " "+path))
+	}
+	fmt.Fprintf(&script, "            (%v) cmd=\"$cmd ${words[i]}\" ;;\n", strings.Join(known, "|"))
+	script.WriteString("            (*) break ;;\n")
+	script.WriteString("        esac\n")
+	script.WriteString("    done\n")
+	script.WriteString("    case \"$cmd\" in\n")
+	fmt.Fprintf(&script, "        (\"\") compadd -- %v ;;\n", strings.Join(words[""], " "))
+	for _, path := range completionPaths() {
+		if _, found := findCommand(path); found && path != "completion" {
+			fmt.Fprintf(&script, "        (%q) if [[ $PREFIX == -* ]]; then compadd -- %v; else _files; fi ;;\n", " "+path, strings.Join(words[path], " "))
+		} else {
+			fmt.Fprintf(&script, "        (%q) compadd -- %v ;;\n", " "+path, strings.Join(words[path], " "))
+		}
+	}
+	script.WriteString("    esac\n")
+	script.WriteString("}\n")
+	fmt.Fprintf(&script, "compdef _%v %v\n", programName, programName)
+	return script.String()
+}
+
+func fishCompletion() string {
+	var script strings.Builder
+	fmt.Fprintf(&script, "# fish completion for %v, load with: %v completion fish | source\n", programName, programName)
+	fmt.Fprintf(&script, "complete -c %v -f\n", programName)
+
+	//Top level commands and groups
+	var top []string
+	for _, cmd := range commands {
+		first := strings.Fields(cmd.name)[0]
+		if !containsWord(top, first) {
+			top = append(top, first)
+		}
+	}
+	for _, name := range top {
+		summary := "Commands for " + name
+		if cmd, found := findCommand(name); found {
+			summary = cmd.summary
+		}
+		fmt.Fprintf(&script, "complete -c %v -n '__fish_use_subcommand' -a %v -d %q\n", programName, name, summary)
+	}
+	fmt.Fprintf(&script, "complete -c %v -n '__fish_use_subcommand' -l version -d %q\n", programName, "Print the version")
+
+	for _, cmd := range commands {
+		parts := strings.Fields(cmd.name)
+		condition := "__fish_seen_subcommand_from " + parts[0]
+		if len(parts) == 2 {
+			//The subcommand itself, offered until one is typed
+			var siblings []string
+			for _, sibling := range subcommands(parts[0]) {
+				siblings = append(siblings, strings.Fields(sibling.name)[1])
+			}
+			fmt.Fprintf(&script, "complete -c %v -n '%v; and not __fish_seen_subcommand_from %v' -a %v -d %q\n",
+				programName, condition, strings.Join(siblings, " "), parts[1], cmd.summary)
+			condition += "; and __fish_seen_subcommand_from " + parts[1]
+		}
+		if cmd.name == "completion" {
+			fmt.Fprintf(&script, "complete -c %v -n '%v' -a 'bash zsh fish'\n", programName, condition)
+			continue
+		}
+		if cmd.flags == nil {
+			continue
+		}
+		fmt.Fprintf(&script, "complete -c %v -n '%v' -F\n", programName, condition)
+		flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		cmd.flags(flags)
+		flags.VisitAll(func(f *flag.Flag) {
+			option := "-l " + f.Name
+			if len(f.Name) == 1 {
+				option = "-s " + f.Name
+			}
+			fmt.Fprintf(&script, "complete -c %v -n '%v' %v -d %q\n", programName, condition, option, f.Usage)
+		})
+	}
+	return script.String()
+}
+
+func runCompletion(args []string) int {
+	if len(args) != 1 || completionShells[args[0]] == nil {
+		printCommandHelp("completion")
+		return exitUsage
+	}
+	fmt.Print(completionShells[args[0]]())
+	return exitOK
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestCompletionScripts(t *testing.T) {
+	for shell, script := range completionShells {
+		text := script()
+		for _, cmd := range commands {
+			//Every word of every command
+			for _, word := range strings.Fields(cmd.name) {
+				if !strings.Contains(text, word) {
+					t.Errorf("%v completion is missing the command %v", shell, word)
+				}
+			}
+			if cmd.flags == nil {
+				continue
+			}
+
+			//Every flag of the command, in the form of the shell
+			flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+			cmd.flags(flags)
+			flags.VisitAll(func(f *flag.Flag) {
+				want := flagName(f.Name)
+				if shell == "fish" {
+					want = "-l " + f.Name
+					if len(f.Name) == 1 {
+						want = "-s " + f.Name
+					}
+				}
+				if !containsCompletion(text, cmd.name, want) {
+					t.Errorf("%v completion of %v is missing %v", shell, cmd.name, want)
+				}
+			})
+		}
+	}
+}
+
+// Whether the line of the script that completes cmd offers word
+func containsCompletion(script, cmd, word string) bool {
+	last := strings.Fields(cmd)[len(strings.Fields(cmd))-1]
+	for _, line := range strings.Split(script, "\n") {
+		//bash and zsh have a case per command, fish a condition naming it
+		inCase := strings.Contains(line, `" `+cmd+`")`)
+		inCondition := strings.Contains(line, "__fish_seen_subcommand_from "+last+"'")
+		if !inCase && !inCondition {
+			continue
+		}
+		words := strings.Fields(strings.NewReplacer(`"`, " ", ";", " ").Replace(line))
+		for i, w := range words {
+			//fish writes the option and its name as two words
+			if w == word || (i > 0 && words[i-1]+" "+w == word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestCommandSummaries(t *testing.T) {
+	convert, _ := findCommand("convert")
+	for _, format := range []string{"text", "html", "json", "ics"} {
+		if !strings.Contains(convert.summary, format) {
+			t.Errorf("the convert summary %q doesn't name the %v output", convert.summary, format)
+		}
+	}
+
+	//index build only takes the flags of the lookup it reads
+	index, _ := findCommand("index build")
+	for _, name := range commandFlags(index) {
+		if name != "--diagnostics" && !containsWord(commandFlags(command{flags: addLookupFlags}), name) {
+			t.Errorf("index build offers %v, which is not a lookup flag", name)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Config files looked for in the working directory, the first one found is read
+var localConfigNames = []string{"itinerary.toml", "itinerary.yaml", "itinerary.yml"}
+
+// Config files looked for in $XDG_CONFIG_HOME/itinerary
+var userConfigNames = []string{"config.toml", "config.yaml", "config.yml"}
+
+// Environment variables are the flag name in capitals, ITINERARY_ON_COLLISION for --on-collision
+const envPrefix = "ITINERARY_"
+
+// A key of a config file with its values, lists have several
+type configEntry struct {
+	key    string
+	values []string
+	source string
+}
+
+// Where every option of the run got its value from, filled by parseFlags
+var optionSources = map[string]string{}
+
+func envName(key string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// The config files that exist, the user's first so the local one overrides it
+func findConfigFiles() []string {
+	var found []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		if path := firstExisting(filepath.Join(dir, "itinerary"), userConfigNames); path != "" {
+			found = append(found, path)
+		}
+	}
+	if path := firstExisting(".", localConfigNames); path != "" {
+		found = append(found, path)
+	}
+	return found
+}
+
+func firstExisting(dir string, names []string) string {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Reads the flat subset of TOML and YAML that a config needs: key = value or key: value,
+// strings, numbers, booleans, lists of strings and comments
+func parseConfig(path string, content string) ([]configEntry, error) {
+	separator := "="
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		separator = ":"
+	}
+
+	var entries []configEntry
+	for i, line := range strings.Split(content, "\n") {
+		source := fmt.Sprintf("%v:%d", path, i+1)
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+
+		//YAML lists may follow their key one item per line
+		if item, isItem := strings.CutPrefix(line, "- "); isItem && separator == ":" && len(entries) > 0 {
+			value, err := configValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", source, err)
+			}
+			last := &entries[len(entries)-1]
+			last.values = append(last.values, value)
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("%v: sections are not supported, keep every option at the top level", source)
+		}
+
+		key, rawValue, found := strings.Cut(line, separator)
+		if !found {
+			return nil, fmt.Errorf("%v: expected key %v value", source, separator)
+		}
+		key = strings.ReplaceAll(strings.TrimSpace(key), "_", "-")
+		rawValue = strings.TrimSpace(rawValue)
+
+		var values []string
+		if list, isList := strings.CutPrefix(rawValue, "["); isList {
+			items, closed := splitConfigList(list)
+			if !closed {
+				return nil, fmt.Errorf("%v: lists have to close on the same line", source)
+			}
+			for _, item := range items {
+				if strings.TrimSpace(item) == "" {
+					continue
+				}
+				value, err := configValue(item)
+				if err != nil {
+					return nil, fmt.Errorf("%v: %v", source, err)
+				}
+				values = append(values, value)
+			}
+		} else if rawValue != "" {
+			value, err := configValue(rawValue)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", source, err)
+			}
+			values = append(values, value)
+		}
+		entries = append(entries, configEntry{key: key, values: values, source: source})
+	}
+	return entries, nil
+}
+
+// Splits the items of an inline list at the commas outside quotes, up to the closing
+// bracket, so "trips, 2024.csv" stays one item
+func splitConfigList(list string) ([]string, bool) {
+	var items []string
+	start := 0
+	var quote byte
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, list[start:i])
+			start = i + 1
+		case c == ']':
+			//Only a comment may follow the list
+			rest := strings.TrimSpace(list[i+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, false
+			}
+			return append(items, list[start:i]), true
+		}
+	}
+	return nil, false
+}
+
+func stripComment(value string) string {
+	if i := strings.Index(value, " #"); i >= 0 {
+		return strings.TrimSpace(value[:i])
+	}
+	return strings.TrimSpace(value)
+}
+
+func configValue(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		end := strings.LastIndex(raw, `"`)
+		if end == 0 {
+			return "", fmt.Errorf("unclosed quote in %v", raw)
+		}
+		return strconv.Unquote(raw[:end+1])
+	case strings.HasPrefix(raw, "'"):
+		end := strings.LastIndex(raw, "'")
+		if end == 0 {
+			return "", fmt.Errorf("unclosed quote in %v", raw)
+		}
+		return raw[1:end], nil
+	}
+	return stripComment(raw), nil
+}
+
+// Everything the config files set, read once per run
+var configEntries []configEntry
+var configLoaded bool
+
+func loadConfig() ([]configEntry, error) {
+	if configLoaded {
+		return configEntries, nil
+	}
+	configLoaded = true
+	for _, path := range findConfigFiles() {
+		content, err := loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := parseConfig(path, content)
+		if err != nil {
+			return nil, err
+		}
+		configEntries = append(configEntries, entries...)
+	}
+	return configEntries, nil
+}
+
+// Parses the flags of a command, then fills the options they leave out from the
+// environment and the config files, so a flag beats a variable and a variable beats a file
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	known := allOptionNames()
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	entries, err := loadConfig()
+	if err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		flushDiagnostics()
+		return err
+	}
+
+	flags.VisitAll(func(f *flag.Flag) {
+		optionSources[f.Name] = "default"
+	})
+	fromFlags := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		fromFlags[f.Name] = true
+		optionSources[f.Name] = "flag"
+	})
+
+	//Each source replaces the lookups of the one before
+	setOption := func(name string, values []string, source string) error {
+		if name == "lookup" {
+			lookupFlags = nil
+		}
+		for _, value := range values {
+			if err := flags.Set(name, value); err != nil {
+				return fmt.Errorf("%v: invalid value %q for %v: %v", source, value, name, err)
+			}
+		}
+		optionSources[name] = source
+		return nil
+	}
+
+	for _, entry := range entries {
+		if flags.Lookup(entry.key) == nil {
+			if !known[entry.key] {
+				reportf(severityWarning, diagUsage, "%v: unknown option %v", entry.source, entry.key)
+			}
+			continue
+		}
+		if fromFlags[entry.key] || os.Getenv(envName(entry.key)) != "" {
+			continue
+		}
+		if err := setOption(entry.key, entry.values, entry.source); err != nil {
+			reportf(severityError, diagUsage, "%v", err)
+			flushDiagnostics()
+			return err
+		}
+	}
+
+	var envErr error
+	flags.VisitAll(func(f *flag.Flag) {
+		value := os.Getenv(envName(f.Name))
+		if value == "" || fromFlags[f.Name] || envErr != nil {
+			return
+		}
+		values := []string{value}
+		if f.Name == "lookup" {
+			values = filepath.SplitList(value)
+		}
+		envErr = setOption(f.Name, values, "$"+envName(f.Name))
+	})
+	if envErr != nil {
+		reportf(severityError, diagUsage, "%v", envErr)
+		flushDiagnostics()
+		return envErr
+	}
+	return nil
+}
+
+// Every option a config may set, so a config shared by all commands doesn't warn.
+// Written out rather than registered, registering would reset the globals the flags bind
+var allOptions = []string{
+	"o", "overwrite", "r", "rename", "units", "offset-style", "backup", "on-collision", "strict", "ascii",
+	"lookup", "coords", "duplicates", "lookup-format", "columns", "airlines", "input-format", "diagnostics",
+	//Options that only some commands have
+	"clean", "near", "radius", "limit", "out-dir", "format",
+}
+
+func allOptionNames() map[string]bool {
+	names := map[string]bool{}
+	for _, name := range allOptions {
+		names[name] = true
+	}
+	return names
+}
+
+func runConfigShow(args []string) int {
+	showFlags := newFlagSet("config show")
+	registerConvertFlags(showFlags)
+	if err := parseFlags(showFlags, args); err != nil {
+		return usageExit(err)
+	}
+	defer flushDiagnostics()
+
+	files := findConfigFiles()
+	if len(files) == 0 {
+		fmt.Printf("%sNo config file found%s, looked for %v in the working directory and %v in %v\n", Yellow, Reset,
+			strings.Join(localConfigNames, ", "), strings.Join(userConfigNames, ", "), filepath.Join("$XDG_CONFIG_HOME", "itinerary"))
+	} else {
+		fmt.Printf("%sConfig files:%s %v\n", Blue, Reset, strings.Join(files, ", "))
+	}
+
+	var names []string
+	showFlags.VisitAll(func(f *flag.Flag) {
+		if f.Name != "h" {
+			names = append(names, f.Name)
+		}
+	})
+	sort.Strings(names)
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "Option\tValue\tSource")
+	for _, name := range names {
+		value := showFlags.Lookup(name).Value.String()
+		if name == "lookup" && value == "" {
+			value = embeddedLookupPath
+		}
+		if name == "airlines" && value == "" {
+			value = embeddedAirlinesPath
+		}
+		fmt.Fprintf(table, "%v\t%v\t%v\n", name, value, optionSources[name])
+	}
+	table.Flush()
+	return exitOK
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    map[string][]string
+	}{
+		{"itinerary.toml", "units = \"mi\"\nstrict = true # fail on unknown codes\nlookup_format = 'csv'",
+			map[string][]string{"units": {"mi"}, "strict": {"true"}, "lookup-format": {"csv"}}},
+		{"itinerary.toml", `lookup = ["a.csv", 'trips, 2024.csv', "say \"hi\".csv"] # three`,
+			map[string][]string{"lookup": {"a.csv", "trips, 2024.csv", `say "hi".csv`}}},
+		{"itinerary.yaml", "---\nunits: nm\nlookup:\n  - a.csv\n  - \"b, c.csv\"\nbackup: history",
+			map[string][]string{"units": {"nm"}, "lookup": {"a.csv", "b, c.csv"}, "backup": {"history"}}},
+		{"itinerary.yml", "lookup: [a.csv, \"b, c.csv\"]",
+			map[string][]string{"lookup": {"a.csv", "b, c.csv"}}},
+	}
+	for _, test := range tests {
+		entries, err := parseConfig(test.path, test.content)
+		if err != nil {
+			t.Errorf("%v %q: %v", test.path, test.content, err)
+			continue
+		}
+		got := map[string][]string{}
+		for _, entry := range entries {
+			got[entry.key] = entry.values
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v %q = %v, want %v", test.path, test.content, got, test.want)
+		}
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    string
+	}{
+		{"itinerary.toml", "units = \"mi\"\n[convert]\nstrict = true", "itinerary.toml:2: sections are not supported"},
+		{"itinerary.toml", "units \"mi\"", "itinerary.toml:1: expected key = value"},
+		{"itinerary.yaml", "units = mi", "itinerary.yaml:1: expected key : value"},
+		{"itinerary.toml", "lookup = [\"a.csv\",\n\"b.csv\"]", "itinerary.toml:1: lists have to close on the same line"},
+		{"itinerary.toml", "lookup = [\"a.csv\", \"b.csv\"", "itinerary.toml:1: lists have to close on the same line"},
+		{"itinerary.toml", "lookup = [\"a.csv\"] b.csv", "itinerary.toml:1: lists have to close on the same line"},
+		{"itinerary.toml", "units = \"mi", "itinerary.toml:1: unclosed quote"},
+		{"itinerary.yaml", "lookup:\n  - 'a.csv", "itinerary.yaml:2: unclosed quote"},
+	}
+	for _, test := range tests {
+		_, err := parseConfig(test.path, test.content)
+		if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("%v %q: got %v, want %v", test.path, test.content, err, test.want)
+		}
+	}
+}
+
+func TestOptionPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	config := "units = \"mi\"\noffset-style = \"gmt\"\nbackup = \"bak\"\ncolumns = \"name=Airport\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "itinerary.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "none"))
+	t.Setenv("HOME", filepath.Join(dir, "none"))
+	t.Setenv(envName("units"), "km")
+	t.Setenv(envName("offset-style"), "utc")
+	defer func() {
+		os.Chdir(wd)
+		configEntries, configLoaded = nil, false
+		offsetStyle, backupMode, columnMapping = "iso", "", ""
+		optionSources = map[string]string{}
+		diagnostics = nil
+	}()
+	configEntries, configLoaded = nil, false
+
+	flags := newFlagSet("convert")
+	opts := registerConvertFlags(flags)
+	if err := parseFlags(flags, []string{"--units", "nm"}); err != nil {
+		t.Fatal(err)
+	}
+	//A flag beats a variable, a variable beats a file, a file beats the default
+	got := []string{*opts.units, offsetStyle, backupMode, columnMapping, *opts.onCollision}
+	want := []string{"nm", "utc", "bak", "name=Airport", "prompt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("units, offset-style, backup, columns, on-collision = %v, want %v", got, want)
+	}
+	sources := []string{optionSources["units"], optionSources["offset-style"], optionSources["backup"], optionSources["on-collision"]}
+	wantSources := []string{"flag", "$ITINERARY_OFFSET_STYLE", "itinerary.toml:3", "default"}
+	if !reflect.DeepEqual(sources, wantSources) {
+		t.Errorf("sources = %v, want %v", sources, wantSources)
+	}
+}
+
+func TestAllOptionNames(t *testing.T) {
+	defer func() { offsetStyle, asciiOutput = "iso", false }()
+	offsetStyle, asciiOutput = "gmt", true
+	known := allOptionNames()
+	if offsetStyle != "gmt" || !asciiOutput {
+		t.Errorf("listing the options reset them to offset-style %v, ascii %v", offsetStyle, asciiOutput)
+	}
+
+	//Every option of a conversion is known, so a config setting it doesn't warn
+	flags := newFlagSet("convert")
+	registerConvertFlags(flags)
+	flags.VisitAll(func(f *flag.Flag) {
+		if !known[f.Name] {
+			t.Errorf("option %v is missing from allOptions", f.Name)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Order of the two values in the coordinates column, set with --coords
+var coordinateOrder = "lonlat"
+
+var coordinateOrders = map[string]bool{
+	"lonlat": true,
+	"latlon": true,
+}
+
+var errSwappedCoordinates = errors.New("coordinates are in the opposite order")
+
+func validLatitude(lat float64) bool {
+	return lat >= -90 && lat <= 90
+}
+
+func validLongitude(lon float64) bool {
+	return lon >= -180 && lon <= 180
+}
+
+func parseCoordinates(coordinates string, order string) (lat float64, lon float64, err error) {
+	//Expect exactly two numbers separated by a comma
+	parts := strings.Split(coordinates, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected two values, got %d", len(parts))
+	}
+	first, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid number %q", strings.TrimSpace(parts[0]))
+	}
+	second, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid number %q", strings.TrimSpace(parts[1]))
+	}
+
+	//Apply the column convention
+	lat, lon = second, first
+	if order == "latlon" {
+		lat, lon = first, second
+	}
+
+	//Values that only make sense the other way round were written in the wrong order
+	if !validLatitude(lat) && validLatitude(lon) && validLongitude(lat) {
+		return 0, 0, errSwappedCoordinates
+	}
+	if !validLatitude(lat) {
+		return 0, 0, fmt.Errorf("latitude %v out of range", lat)
+	}
+	if !validLongitude(lon) {
+		return 0, 0, fmt.Errorf("longitude %v out of range", lon)
+	}
+	return lat, lon, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCoordinates(t *testing.T) {
+	tests := []struct {
+		coordinates string
+		order       string
+		lat, lon    float64
+		err         string //empty when the values are read
+	}{
+		{"-0.461941, 51.4706", "lonlat", 51.4706, -0.461941, ""},
+		{"51.4706, -0.461941", "latlon", 51.4706, -0.461941, ""},
+		{"180,-90", "lonlat", -90, 180, ""},
+		//A latitude past 90 that works as a longitude was written the other way round
+		{"51.4706, -118.408", "lonlat", 0, 0, errSwappedCoordinates.Error()},
+		{"-118.408, 33.9425", "latlon", 0, 0, errSwappedCoordinates.Error()},
+		//Values that work neither way are out of range
+		{"10, 195", "lonlat", 0, 0, "latitude 195 out of range"},
+		{"200, 95", "lonlat", 0, 0, "latitude 95 out of range"},
+		{"190, 10", "lonlat", 0, 0, "longitude 190 out of range"},
+		{"51.4706", "lonlat", 0, 0, "expected two values, got 1"},
+		{"1,2,3", "lonlat", 0, 0, "expected two values, got 3"},
+		{"west, 51.4706", "lonlat", 0, 0, `invalid number "west"`},
+		{"-0.46, ", "lonlat", 0, 0, `invalid number ""`},
+	}
+	for _, test := range tests {
+		lat, lon, err := parseCoordinates(test.coordinates, test.order)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("parseCoordinates(%q, %v) = %v, want %v", test.coordinates, test.order, err, test.err)
+			}
+			continue
+		}
+		if err != nil || lat != test.lat || lon != test.lon {
+			t.Errorf("parseCoordinates(%q, %v) = %v, %v, %v, want %v, %v", test.coordinates, test.order, lat, lon, err, test.lat, test.lon)
+		}
+	}
+}
+
+func TestCoordsFlag(t *testing.T) {
+	defer func() { coordinateOrder, airports, diagnostics = "lonlat", nil, nil }()
+
+	//The same lookup written latitude first
+	path := filepath.Join(t.TempDir(), "latlon.csv")
+	content := "municipality,name,iata_code,icao_code,iso_country,coordinates\n" +
+		"London,London Heathrow Airport,LHR,EGLL,GB,\"51.4706, -0.461941\"\n" +
+		"Los Angeles,Los Angeles International Airport,LAX,KLAX,US,\"33.9425, -118.408\"\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	//Read the default way only Heathrow fits, Los Angeles is reported as swapped
+	diagnostics = nil
+	if err := loadAirports(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(airports) != 1 || airports[0].IATA_Code != "LHR" {
+		t.Errorf("loaded %v in lonlat order, want only LHR", airports)
+	}
+	if len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, "swapped coordinates, try --coords") {
+		t.Errorf("diagnostics = %v, want one swapped coordinates warning", diagnostics)
+	}
+
+	//--coords latlon reads both the right way round
+	flags := newFlagSet("convert")
+	addLookupFlags(flags)
+	if err := flags.Parse([]string{"--coords", "latlon"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkLookupFlags(); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics = nil
+	if err := loadAirports(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(airports) != 2 || len(diagnostics) != 0 {
+		t.Fatalf("loaded %v with %v in latlon order, want both without warnings", airports, diagnostics)
+	}
+	if airports[0].Latitude != 51.4706 || airports[0].Longitude != -0.461941 {
+		t.Errorf("Heathrow at %v,%v, want 51.4706,-0.461941", airports[0].Latitude, airports[0].Longitude)
+	}
+
+	if err := flags.Parse([]string{"--coords", "north-east"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkLookupFlags(); err == nil {
+		t.Error("an unknown coordinate order gave no error")
+	}
+}
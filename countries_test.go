@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestPlaceCountries(t *testing.T) {
+	defer func() { airports = nil }()
+	airports = []Airport{
+		{Name: "London Heathrow Airport", Municipality: "London", ISO_Country: "GB", IATA_Code: "LHR", ICAO_Code: "EGLL"},
+		{Name: "Pristina International Airport", Municipality: "Pristina", ISO_Country: "ZZ", IATA_Code: "PRN", ICAO_Code: "BKPR"},
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Welcome to ^#LHR!", "Welcome to United Kingdom!"},
+		{"Welcome to ^##EGLL.", "Welcome to United Kingdom."},
+		{"Landing at ^^#LHR", "Landing at London Heathrow Airport, London, United Kingdom"},
+		//A country missing from the table is shown by its code
+		{"Welcome to ^#PRN", "Welcome to ZZ"},
+		{"Landing at ^^##BKPR", "Landing at Pristina International Airport, Pristina, ZZ"},
+		//Unknown airports and glued codes are left as written
+		{"Welcome to ^#ZZZ and ^##ZZZZ", "Welcome to ^#ZZZ and ^##ZZZZ"},
+		{"A^#LHR ^#LHRX", "A^#LHR ^#LHRX"},
+		{"^#LHR, ^#PRN", "United Kingdom, ZZ"},
+	}
+	for _, test := range tests {
+		if got := placeCountries(test.input); got != test.want {
+			t.Errorf("placeCountries(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestCountryName(t *testing.T) {
+	tests := map[string]string{"GB": "United Kingdom", "gb": "United Kingdom", "CI": "Côte d'Ivoire", "ZZ": "ZZ", "": ""}
+	for code, want := range tests {
+		if got := countryName(code); got != want {
+			t.Errorf("countryName(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Exit codes, one per class of failure
+const (
+	exitOK             = 0 //Everything went fine, or the user cancelled
+	exitProblems       = 1 //check found problems, or --strict found unresolved codes
+	exitUsage          = 2 //Wrong arguments or flag values
+	exitInput          = 3 //Input could not be read
+	exitLookupNotFound = 4 //Airport lookup could not be read
+	exitLookupInvalid  = 5 //Airport lookup is malformed
+	exitOutput         = 6 //Output could not be written
+)
+
+type severity string
+
+const (
+	severityInfo    severity = "info"
+	severityWarning severity = "warning"
+	severityError   severity = "error"
+)
+
+// Diagnostic codes
+const (
+	diagUsage           = "usage"
+	diagInputMissing    = "input-missing"
+	diagInputMalformed  = "input-malformed"
+	diagLookupMissing   = "lookup-missing"
+	diagLookupMalformed = "lookup-malformed"
+	diagLookupRow       = "lookup-row-skipped"
+	diagLookupDuplicate = "lookup-duplicate"
+	diagLookupOverride  = "lookup-override"
+	diagIndexStale      = "index-stale"
+	diagTokenMalformed  = "token-malformed"
+	diagTokenInvalid    = "token-invalid"
+	diagOffsetInvalid   = "offset-invalid"
+	diagCodeUnresolved  = "code-unresolved"
+	diagCodeGlued       = "code-glued"
+	diagTimeOrder       = "time-order"
+	diagSegmentRoute    = "segment-route"
+	diagFileCollision   = "file-collision"
+	diagOutputFailed    = "output-failed"
+)
+
+type Diagnostic struct {
+	Code        string       `json:"code"`
+	Severity    severity     `json:"severity"`
+	File        string       `json:"file,omitempty"`
+	Line        int          `json:"line,omitempty"`
+	Column      int          `json:"column,omitempty"`
+	Message     string       `json:"message"`
+	Suggestions []suggestion `json:"suggestions,omitempty"`
+}
+
+// Everything reported during the run, in order
+var diagnostics []Diagnostic
+
+// Set with --diagnostics
+var diagnosticsFormat = "text"
+
+func report(d Diagnostic) {
+	diagnostics = append(diagnostics, d)
+}
+
+func reportf(sev severity, code string, format string, args ...any) {
+	report(Diagnostic{Code: code, Severity: sev, Message: fmt.Sprintf(format, args...)})
+}
+
+func reportAt(sev severity, code string, file string, line int, column int, format string, args ...any) {
+	report(Diagnostic{Code: code, Severity: sev, File: file, Line: line, Column: column, Message: fmt.Sprintf(format, args...)})
+}
+
+// An unknown --diagnostics falls back to text, so the error about it can still be printed
+func checkDiagnosticsFormat() error {
+	if diagnosticsFormat != "text" && diagnosticsFormat != "json" {
+		format := diagnosticsFormat
+		diagnosticsFormat = "text"
+		return fmt.Errorf("unknown diagnostics format %v", format)
+	}
+	return nil
+}
+
+func countDiagnostics(sev severity) int {
+	count := 0
+	for _, d := range diagnostics {
+		if d.Severity == sev {
+			count++
+		}
+	}
+	return count
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (d Diagnostic) String() string {
+	var text strings.Builder
+	if d.File != "" {
+		text.WriteString(d.File + ":")
+		if d.Line > 0 {
+			fmt.Fprintf(&text, "%d:%d:", d.Line, d.Column)
+		}
+		text.WriteString(" ")
+	}
+	fmt.Fprintf(&text, "%v: %v [%v]", d.Severity, d.Message, d.Code)
+	if len(d.Suggestions) > 0 {
+		var names []string
+		for _, s := range d.Suggestions {
+			names = append(names, s.Code+" ("+s.Name+")")
+		}
+		text.WriteString(" - did you mean " + strings.Join(names, ", ") + "?")
+	}
+	return text.String()
+}
+
+func severityColor(sev severity) string {
+	switch sev {
+	case severityError:
+		return Red
+	case severityWarning:
+		return Yellow
+	}
+	return Blue
+}
+
+func renderDiagnostics(w io.Writer, format string, color bool) {
+	if format == "json" {
+		list := diagnostics
+		if list == nil {
+			list = []Diagnostic{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(list)
+		return
+	}
+
+	for _, d := range diagnostics {
+		if color {
+			fmt.Fprintf(w, "%s%v%s\n", severityColor(d.Severity), d, Reset)
+		} else {
+			fmt.Fprintln(w, d)
+		}
+	}
+}
+
+// Prints the collected diagnostics to stderr, coloured only on a terminal
+func flushDiagnostics() {
+	renderDiagnostics(os.Stderr, diagnosticsFormat, isTerminal(os.Stderr))
+	diagnostics = nil
+}
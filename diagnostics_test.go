@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testDiagnostics = []Diagnostic{
+	{Code: diagLookupRow, Severity: severityWarning, File: "airports.csv", Message: "could not read 2 airport records, blank field"},
+	{Code: diagCodeUnresolved, Severity: severityError, File: "input.txt", Line: 1, Column: 12, Message: "unknown airport code #LQX",
+		Suggestions: []suggestion{{Code: "#YQX", Name: "Gander International Airport"}}},
+	{Code: diagUsage, Severity: severityInfo, Message: "nothing to do"},
+}
+
+func TestRenderDiagnosticsText(t *testing.T) {
+	defer func() { diagnostics = nil }()
+	diagnostics = testDiagnostics
+
+	var plain bytes.Buffer
+	renderDiagnostics(&plain, "text", false)
+	want := "airports.csv: warning: could not read 2 airport records, blank field [lookup-row-skipped]\n" +
+		"input.txt:1:12: error: unknown airport code #LQX [code-unresolved] - did you mean #YQX (Gander International Airport)?\n" +
+		"info: nothing to do [usage]\n"
+	if plain.String() != want {
+		t.Errorf("text diagnostics =\n%v\nwant\n%v", plain.String(), want)
+	}
+
+	//Colour only when asked for, one colour per line
+	var colored bytes.Buffer
+	renderDiagnostics(&colored, "text", true)
+	if !strings.HasPrefix(colored.String(), Yellow) || strings.Count(colored.String(), Reset) != 3 {
+		t.Errorf("coloured diagnostics = %q", colored.String())
+	}
+	if strings.Contains(plain.String(), "\033[") {
+		t.Errorf("plain diagnostics contain escape codes: %q", plain.String())
+	}
+}
+
+func TestRenderDiagnosticsJSON(t *testing.T) {
+	defer func() { diagnostics = nil }()
+	diagnostics = testDiagnostics
+
+	var output bytes.Buffer
+	renderDiagnostics(&output, "json", true)
+	if strings.Contains(output.String(), "\033[") {
+		t.Errorf("JSON diagnostics contain escape codes: %q", output.String())
+	}
+	var got []Diagnostic
+	if err := json.Unmarshal(output.Bytes(), &got); err != nil {
+		t.Fatalf("JSON diagnostics don't parse: %v\n%v", err, output.String())
+	}
+	if !reflect.DeepEqual(got, testDiagnostics) {
+		t.Errorf("JSON diagnostics read back as %+v, want %+v", got, testDiagnostics)
+	}
+
+	//Empty fields are left out
+	var fields []map[string]any
+	json.Unmarshal(output.Bytes(), &fields)
+	if _, exists := fields[2]["file"]; exists {
+		t.Errorf("a diagnostic without a file has one in JSON: %v", fields[2])
+	}
+	if fields[1]["line"] != 1.0 || fields[1]["severity"] != "error" {
+		t.Errorf("JSON diagnostic = %v, want line 1 and severity error", fields[1])
+	}
+
+	//No diagnostics is an empty array, not null
+	diagnostics = nil
+	output.Reset()
+	renderDiagnostics(&output, "json", false)
+	if strings.TrimSpace(output.String()) != "[]" {
+		t.Errorf("no JSON diagnostics = %q, want []", output.String())
+	}
+}
+
+func TestCheckDiagnosticsFormat(t *testing.T) {
+	defer func() { diagnosticsFormat = "text" }()
+	for _, format := range []string{"text", "json"} {
+		diagnosticsFormat = format
+		if err := checkDiagnosticsFormat(); err != nil || diagnosticsFormat != format {
+			t.Errorf("checkDiagnosticsFormat with %v = %v, format now %v", format, err, diagnosticsFormat)
+		}
+	}
+	//An unknown format is reported in text
+	diagnosticsFormat = "xml"
+	if err := checkDiagnosticsFormat(); err == nil || err.Error() != "unknown diagnostics format xml" || diagnosticsFormat != "text" {
+		t.Errorf("checkDiagnosticsFormat with xml = %v, format now %v", err, diagnosticsFormat)
+	}
+}
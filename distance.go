@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Mean earth radius in kilometres
+const earthRadiusKm = 6371.0088
+
+// Kilometres per unit for the --units flag
+var distanceUnits = map[string]float64{
+	"km": 1,
+	"mi": 1.609344,
+	"nm": 1.852,
+}
+
+var distanceUnit = "km"
+
+// Stands in for '#' inside unresolved DIST tokens so the name passes leave them verbatim
+const heldHash = "\uE000"
+
+// Matches DIST(#XXX,##XXXX) with any mix of IATA and ICAO codes, or DIST(TOTAL)
+var distanceToken = regexp.MustCompile(`DIST\((?:(##[A-Z]{4}|#[A-Z]{3}),\s*(##[A-Z]{4}|#[A-Z]{3})|TOTAL)\)`)
+
+func findAirportByCode(code string) (Airport, bool) {
+	if strings.HasPrefix(code, "##") {
+		return findAirportByICAO(code[2:])
+	}
+	return findAirportByIATA(code[1:])
+}
+
+func greatCircleDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	//Haversine formula
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+	h := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func airportDistance(from, to Airport) float64 {
+	return greatCircleDistance(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+}
+
+func formatDistance(km float64) string {
+	return strconv.Itoa(int(math.Round(km/distanceUnits[distanceUnit]))) + " " + distanceUnit
+}
+
+// DIST(TOTAL) is the whole trip, which a paragraph on its own doesn't know
+func placeDistancesWith(input string, total float64) string {
+	return distanceToken.ReplaceAllStringFunc(input, func(match string) string {
+		codes := distanceToken.FindStringSubmatch(match)
+
+		//DIST(TOTAL) sums every leg of the itinerary
+		if codes[1] == "" {
+			return formatDistance(total)
+		}
+
+		from, foundFrom := findAirportByCode(codes[1])
+		to, foundTo := findAirportByCode(codes[2])
+		if !foundFrom || !foundTo {
+			return strings.ReplaceAll(match, "#", heldHash)
+		}
+		return formatDistance(airportDistance(from, to))
+	})
+}
+
+func restoreDistances(input string) string {
+	return strings.ReplaceAll(input, heldHash, "#")
+}
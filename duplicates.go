@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// What to do when two lookup rows share a code, set with --duplicates
+var duplicatePolicy = "first"
+
+var duplicatePolicies = map[string]bool{
+	"first": true,
+	"last":  true,
+	"error": true,
+}
+
+// A lookup row that passed validation
+type lookupRow struct {
+	line    int
+	fields  []string
+	airport Airport
+}
+
+// Two rows sharing a code
+type codeConflict struct {
+	line        int
+	otherLine   int
+	kind        string
+	code        string
+	conflicting bool
+}
+
+func (c codeConflict) String() string {
+	if c.conflicting {
+		return fmt.Sprintf("%v code %v conflicts with line %d", c.kind, c.code, c.otherLine)
+	}
+	return fmt.Sprintf("%v code %v repeats line %d", c.kind, c.code, c.otherLine)
+}
+
+func sameAirport(a, b Airport) bool {
+	//Providers round coordinates differently, a hundredth of a degree is about a kilometre
+	return strings.EqualFold(a.Name, b.Name) &&
+		strings.EqualFold(a.Municipality, b.Municipality) &&
+		a.ISO_Country == b.ISO_Country &&
+		a.IATA_Code == b.IATA_Code &&
+		a.ICAO_Code == b.ICAO_Code &&
+		math.Abs(a.Latitude-b.Latitude) < 0.01 &&
+		math.Abs(a.Longitude-b.Longitude) < 0.01
+}
+
+// What the duplicate policy needs of a row: its line, the IATA and ICAO codes it is known
+// by and whether two rows sharing a code describe the same thing
+type duplicateKeys[T any] struct {
+	line  func(row T) int
+	codes func(row T) (iata string, icao string)
+	same  func(a, b T) bool
+}
+
+var airportRowKeys = duplicateKeys[lookupRow]{
+	line:  func(row lookupRow) int { return row.line },
+	codes: func(row lookupRow) (string, string) { return row.airport.IATA_Code, row.airport.ICAO_Code },
+	same:  func(a, b lookupRow) bool { return sameAirport(a.airport, b.airport) },
+}
+
+func applyDuplicatePolicy[T any](rows []T, policy string, keys duplicateKeys[T]) ([]T, []codeConflict, error) {
+	kept := make([]bool, len(rows))
+	byIATA := map[string]int{}
+	byICAO := map[string]int{}
+	var conflicts []codeConflict
+	var hardConflicts int
+
+	for i, row := range rows {
+		iata, icao := keys.codes(row)
+
+		//Earlier rows still in use that share a code with this one
+		var clashes []int
+		if j, exists := byIATA[iata]; exists {
+			clashes = append(clashes, j)
+			conflicts = append(conflicts, codeConflict{keys.line(row), keys.line(rows[j]), "IATA", iata, !keys.same(rows[j], row)})
+		}
+		if j, exists := byICAO[icao]; exists {
+			if len(clashes) == 0 || clashes[0] != j {
+				clashes = append(clashes, j)
+			}
+			conflicts = append(conflicts, codeConflict{keys.line(row), keys.line(rows[j]), "ICAO", icao, !keys.same(rows[j], row)})
+		}
+		for _, j := range clashes {
+			if !keys.same(rows[j], row) {
+				hardConflicts++
+			}
+		}
+
+		if len(clashes) > 0 && policy != "last" {
+			continue
+		}
+
+		//The last row wins, so the earlier ones give up their codes
+		for _, j := range clashes {
+			kept[j] = false
+			otherIATA, otherICAO := keys.codes(rows[j])
+			if byIATA[otherIATA] == j {
+				delete(byIATA, otherIATA)
+			}
+			if byICAO[otherICAO] == j {
+				delete(byICAO, otherICAO)
+			}
+		}
+		kept[i] = true
+		byIATA[iata] = i
+		byICAO[icao] = i
+	}
+
+	//Rows that are exact copies are merged even with the error policy
+	if policy == "error" && hardConflicts > 0 {
+		return nil, conflicts, fmt.Errorf("%w: %d rows with conflicting codes", errLookupMalformed, hardConflicts)
+	}
+
+	var result []T
+	for i, row := range rows {
+		if kept[i] {
+			result = append(result, row)
+		}
+	}
+	return result, conflicts, nil
+}
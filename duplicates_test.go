@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyDuplicatePolicy(t *testing.T) {
+	heathrow := Airport{Name: "London Heathrow Airport", Municipality: "London", ISO_Country: "GB", IATA_Code: "LHR", ICAO_Code: "EGLL", Latitude: 51.4706, Longitude: -0.461941}
+	//The same airport from a provider that rounds more
+	rounded := heathrow
+	rounded.Name, rounded.Latitude, rounded.Longitude = "LONDON HEATHROW AIRPORT", 51.471, -0.462
+	renamed := heathrow
+	renamed.Name = "Heathrow"
+	gatwick := Airport{Name: "London Gatwick Airport", Municipality: "London", ISO_Country: "GB", IATA_Code: "LGW", ICAO_Code: "EGKK", Latitude: 51.148, Longitude: -0.190}
+	//Gatwick's IATA code with Heathrow's ICAO code
+	mixed := gatwick
+	mixed.ICAO_Code = "EGLL"
+
+	rowsOf := func(airports ...Airport) []lookupRow {
+		var rows []lookupRow
+		for i, airport := range airports {
+			rows = append(rows, lookupRow{line: i + 2, airport: airport})
+		}
+		return rows
+	}
+	tests := []struct {
+		name        string
+		rows        []lookupRow
+		policy      string
+		kept        []int //lines of the rows kept
+		conflicts   int
+		conflicting int
+		err         bool
+	}{
+		{"no duplicates", rowsOf(heathrow, gatwick), "first", []int{2, 3}, 0, 0, false},
+		{"copy, first", rowsOf(heathrow, rounded), "first", []int{2}, 2, 0, false},
+		{"copy, last", rowsOf(heathrow, rounded), "last", []int{3}, 2, 0, false},
+		{"copy, error", rowsOf(heathrow, rounded), "error", []int{2}, 2, 0, false},
+		{"conflict, first", rowsOf(heathrow, renamed), "first", []int{2}, 2, 2, false},
+		{"conflict, last", rowsOf(heathrow, renamed), "last", []int{3}, 2, 2, false},
+		{"conflict, error", rowsOf(heathrow, renamed), "error", nil, 2, 2, true},
+		//A row sharing one code with each of two rows takes both their places
+		{"two rows, first", rowsOf(heathrow, gatwick, mixed), "first", []int{2, 3}, 2, 2, false},
+		{"two rows, last", rowsOf(heathrow, gatwick, mixed), "last", []int{4}, 2, 2, false},
+	}
+	for _, test := range tests {
+		rows, conflicts, err := applyDuplicatePolicy(test.rows, test.policy, airportRowKeys)
+		if (err != nil) != test.err {
+			t.Errorf("%v: error %v, want one: %v", test.name, err, test.err)
+		}
+		if err != nil && !errors.Is(err, errLookupMalformed) {
+			t.Errorf("%v: error %v is not a malformed lookup", test.name, err)
+		}
+		var kept []int
+		for _, row := range rows {
+			kept = append(kept, row.line)
+		}
+		if !reflect.DeepEqual(kept, test.kept) {
+			t.Errorf("%v: kept lines %v, want %v", test.name, kept, test.kept)
+		}
+		conflicting := 0
+		for _, conflict := range conflicts {
+			if conflict.conflicting {
+				conflicting++
+			}
+		}
+		if len(conflicts) != test.conflicts || conflicting != test.conflicting {
+			t.Errorf("%v: %d shared codes, %d conflicting, want %d, %d: %v", test.name, len(conflicts), conflicting, test.conflicts, test.conflicting, conflicts)
+		}
+	}
+}
+
+func TestCodeConflictString(t *testing.T) {
+	if got := (codeConflict{3, 2, "IATA", "LHR", true}).String(); got != "IATA code LHR conflicts with line 2" {
+		t.Errorf("conflict = %q", got)
+	}
+	if got := (codeConflict{3, 2, "ICAO", "EGLL", false}).String(); got != "ICAO code EGLL repeats line 2" {
+		t.Errorf("repeat = %q", got)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// The bundled lookup, used when no lookup is given
+//
+//go:embed airport-lookup.csv
+var embeddedLookup string
+
+// Stands in for a path wherever the embedded lookup is read
+const embeddedLookupPath = "embedded:airport-lookup.csv"
+
+// When the data of airport-lookup.csv was exported from its source, release builds set it
+// with -ldflags "-X main.embeddedLookupDate=..."
+var embeddedLookupDate = ""
+
+func embeddedLookupDateText() string {
+	if embeddedLookupDate != "" {
+		return embeddedLookupDate
+	}
+
+	//Otherwise the lookup is as old as the commit it was built from
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if date := commitDate(info.Settings); date != "" {
+			return date
+		}
+	}
+	return "unknown"
+}
+
+// The day of the vcs.time go build stamps into a binary built from a checkout
+func commitDate(settings []debug.BuildSetting) string {
+	for _, setting := range settings {
+		if setting.Key != "vcs.time" {
+			continue
+		}
+		if instant, err := time.Parse(time.RFC3339, setting.Value); err == nil {
+			return instant.Format("2006-01-02") + " (commit)"
+		}
+	}
+	return ""
+}
+
+// Set with --lookup, may be repeated
+var lookupFlags lookupList
+
+type lookupList []string
+
+func (l *lookupList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *lookupList) Set(path string) error {
+	*l = append(*l, path)
+	return nil
+}
+
+// The lookups of a command: --lookup first, then the positional ones, else the embedded one
+func chooseLookups(positional []string) []string {
+	lookups := append(append([]string{}, lookupFlags...), positional...)
+	if len(lookups) == 0 {
+		return []string{embeddedLookupPath}
+	}
+	return lookups
+}
+
+func readLookupFile(lookupPath string) (string, error) {
+	if lookupPath == embeddedLookupPath {
+		return embeddedLookup, nil
+	}
+	return loadFile(lookupPath)
+}
+
+func runLookupInfo(args []string) int {
+	infoFlags := newFlagSet("lookup info")
+	addLookupFlags(infoFlags)
+	if err := parseFlags(infoFlags, args); err != nil {
+		return usageExit(err)
+	}
+
+	defer flushDiagnostics()
+
+	if err := checkLookupFlags(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return exitUsage
+	}
+
+	lookupPath := chooseLookups(infoFlags.Args())[0]
+	content, err := readLookupFile(lookupPath)
+	if err != nil {
+		return reportLookupError(lookupPath, fmt.Errorf("%w: %v", errLookupNotFound, err))
+	}
+	date := embeddedLookupDateText()
+	if lookupPath != embeddedLookupPath {
+		info, err := os.Stat(lookupPath)
+		if err == nil {
+			date = info.ModTime().Format("2006-01-02")
+		}
+	}
+
+	records, err := readLookupRecords(lookupPath)
+	if err != nil {
+		return reportLookupError(lookupPath, err)
+	}
+	columns, _ := parseLookupHeader(records[0].fields)
+	summary, err := validateLookupRecords(lookupPath, records, columns)
+	if err != nil {
+		return reportLookupError(lookupPath, err)
+	}
+
+	//Only the numbers are of interest here, lookup validate lists the rows
+	diagnostics = nil
+
+	if isTerminal(os.Stdout) {
+		fmt.Printf("%s%v%s\n", Blue, lookupPath, Reset)
+	} else {
+		fmt.Println(lookupPath)
+	}
+	fmt.Printf("  Format:   %v\n", detectLookupFormat(lookupPath, content))
+	fmt.Printf("  Date:     %v\n", date)
+	fmt.Printf("  Size:     %d bytes\n", len(content))
+	fmt.Printf("  Rows:     %d\n", summary.rows)
+	fmt.Printf("  Airports: %d usable, %d skipped\n", summary.valid, summary.rows-summary.valid)
+	fmt.Printf("  SHA-256:  %x\n", sha256.Sum256([]byte(content)))
+	return exitOK
+}
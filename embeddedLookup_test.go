@@ -0,0 +1,33 @@
+package main
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestRunLookupInfo(t *testing.T) {
+	defer func() { lookupFlags = nil }()
+	exit, output := runCaptured(t, []string{"lookup", "info", writeValidateLookup(t)})
+	if exit != exitOK {
+		t.Errorf("lookup info exited with %d, want %d", exit, exitOK)
+	}
+	if strings.Contains(output, "\033[") {
+		t.Errorf("piped lookup info contains escape codes: %q", output)
+	}
+	for _, want := range []string{"  Format:   csv\n", "  Rows:     7\n", "  Airports: 2 usable, 5 skipped\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("lookup info = %q, want %q in it", output, want)
+		}
+	}
+}
+
+func TestCommitDate(t *testing.T) {
+	settings := []debug.BuildSetting{{Key: "vcs", Value: "git"}, {Key: "vcs.time", Value: "2024-03-05T10:20:30Z"}}
+	if got := commitDate(settings); got != "2024-03-05 (commit)" {
+		t.Errorf("commitDate = %q, want 2024-03-05 (commit)", got)
+	}
+	if got := commitDate([]debug.BuildSetting{{Key: "vcs", Value: "git"}}); got != "" {
+		t.Errorf("commitDate without vcs.time = %q, want nothing", got)
+	}
+}
@@ -2,37 +2,29 @@ package main
 
 import (
 	"regexp"
-	"strconv"
 	"strings"
 )
 
-func getOutputString(input string) string {
+// Detect pattern (D|T12|T24)(NNNN-NN-NNTNN:NN(-NN:NN|+NN:NN|Z)) *N - any number
+var timeToken = regexp.MustCompile(`(?:D|T12|T24)\(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}(?:[−+-]\d{2}:\d{2}|Z)\)`)
+
+// Replaces the codes and tokens of markup, total is what DIST(TOTAL) stands for
+func formatMarkup(input string, total float64) string {
+	input = placeDistancesWith(input, total)
+	input = placeCountries(input)
 	input = placeICAONameCities(input)
 	input = placeICAONames(input)
 	input = placeIATANameCities(input)
 	input = placeIATANames(input)
+	input = placeFlights(input)
 	input = placeTimes(input)
-	input = replaceLineBreaks(input)
-	input = cleanUpDoubleWhiteSpaces(input)
+	input = restoreDistances(input)
 	return input
 }
 
-func validOffset(input int) bool {
-	if input < -12 || input > 14 {
-		return false
-	}
-	return true
-}
-
-func prefixZero(input int) string {
-	if input < 10 && input > -10 {
-		if input < 0 {
-			input = -input
-			return "-0" + strconv.Itoa(input)
-		}
-		return "0" + strconv.Itoa(input)
-	}
-	return strconv.Itoa(input)
+// Names go into the text output as they are
+func plainText(text string) string {
+	return text
 }
 
 func isAlphaNumeric(input rune) bool {
@@ -41,6 +33,28 @@ func isAlphaNumeric(input rune) bool {
 		(input >= '0' && input <= '9') // Numbers
 }
 
+// Like ReplaceAllStringFunc, but also gives where the match is, so the characters
+// around it can be checked even when the same code appears several times
+func replaceMatches(re *regexp.Regexp, input string, replace func(match string, start, end int) string) string {
+	var output strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(input, -1) {
+		output.WriteString(input[last:loc[0]])
+		output.WriteString(replace(input[loc[0]:loc[1]], loc[0], loc[1]))
+		last = loc[1]
+	}
+	output.WriteString(input[last:])
+	return output.String()
+}
+
+// A code glued to a letter or digit on either side is left alone
+func gluedCode(input string, start, end int) bool {
+	if start > 0 && isAlphaNumeric(rune(input[start-1])) {
+		return true
+	}
+	return end < len(input) && isAlphaNumeric(rune(input[end]))
+}
+
 func replaceLineBreaks(input string) string {
 	//Stupid Windows
 	reWhiteSpace := regexp.MustCompile(`\r\n`)
@@ -80,13 +94,9 @@ func placeICAONames(input string) string {
 	//Find pattern ##XXXX
 	re := regexp.MustCompile(`##[A-Z]{4}`)
 
-	return re.ReplaceAllStringFunc(input, func(match string) string {
+	return replaceMatches(re, input, func(match string, start, end int) string {
 		//Check previous or following character for failure exceptions
-		position := strings.Index(input, match)
-		if position > 0 && isAlphaNumeric(rune(input[position-1])) {
-			return match
-		}
-		if position+6 < len(input) && isAlphaNumeric(rune(input[position+6])) {
+		if gluedCode(input, start, end) {
 			return match
 		}
 
@@ -105,15 +115,15 @@ func placeICAONames(input string) string {
 }
 
 func placeICAONameCities(input string) string {
+	return placeICAONameCitiesWith(input, plainText)
+}
+
+func placeICAONameCitiesWith(input string, write func(text string) string) string {
 	//Find pattern *##XXXX
 	re := regexp.MustCompile(`\*##[A-Z]{4}`)
 
-	return re.ReplaceAllStringFunc(input, func(match string) string {
-		position := strings.Index(input, match)
-		if position > 0 && isAlphaNumeric(rune(input[position-1])) {
-			return match
-		}
-		if position+7 < len(input) && isAlphaNumeric(rune(input[position+7])) {
+	return replaceMatches(re, input, func(match string, start, end int) string {
+		if gluedCode(input, start, end) {
 			return match
 		}
 
@@ -122,7 +132,7 @@ func placeICAONameCities(input string) string {
 		//Match it with an airport
 		for _, airport := range airports {
 			if airport.ICAO_Code == airportCode {
-				return airport.Municipality
+				return write(airport.Municipality)
 			}
 		}
 
@@ -135,13 +145,9 @@ func placeIATANames(input string) string {
 	//Find pattern #XXX
 	re := regexp.MustCompile(`#[A-Z]{3}`)
 
-	return re.ReplaceAllStringFunc(input, func(match string) string {
+	return replaceMatches(re, input, func(match string, start, end int) string {
 		//Check previous or following character for failure exceptions
-		position := strings.Index(input, match)
-		if position > 0 && (input[position-1] == '#' || isAlphaNumeric(rune(input[position-1]))) {
-			return match
-		}
-		if position+4 < len(input) && isAlphaNumeric(rune(input[position+4])) {
+		if (start > 0 && input[start-1] == '#') || gluedCode(input, start, end) {
 			return match
 		}
 
@@ -160,16 +166,16 @@ func placeIATANames(input string) string {
 }
 
 func placeIATANameCities(input string) string {
+	return placeIATANameCitiesWith(input, plainText)
+}
+
+func placeIATANameCitiesWith(input string, write func(text string) string) string {
 	//Find pattern *#XXX
-	re := regexp.MustCompile(`\*#[A-Z]{3}(,|.|\s|\n|\t)`)
+	re := regexp.MustCompile(`\*#[A-Z]{3}`)
 
-	return re.ReplaceAllStringFunc(input, func(match string) string {
+	return replaceMatches(re, input, func(match string, start, end int) string {
 		//Check previous or following character for failure exceptions
-		position := strings.Index(input, match)
-		if position > 0 && isAlphaNumeric(rune(input[position-1])) {
-			return match
-		}
-		if position+5 < len(input) && isAlphaNumeric(rune(input[position+5])) {
+		if gluedCode(input, start, end) {
 			return match
 		}
 
@@ -178,7 +184,7 @@ func placeIATANameCities(input string) string {
 		//Match it with an airport
 		for _, airport := range airports {
 			if airport.IATA_Code == airportCode {
-				return airport.Municipality
+				return write(airport.Municipality)
 			}
 		}
 
@@ -188,114 +194,13 @@ func placeIATANameCities(input string) string {
 }
 
 func placeTimes(input string) string {
-	//Detect pattern (D|T12|T24)(NNNN-NN-NNTNN:NN(-NN:00|+NN:00|Z)) *N - any number
-	re := regexp.MustCompile(`(?:D|T12|T24)\(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}(?:[−+-]\d{2}:00|Z)\)`)
-	return re.ReplaceAllStringFunc(input, func(match string) string {
-		//D format - converts into DD-mmm-YYYY
-		if match[0] == 'D' {
-			//Find values
-			year := match[2:6]
-			month := match[7:9]
-			day := match[10:12]
-
-			//Detect if offset moves the day or not
-			// Bonus feature?
-			if len(match) > 22 {
-				//Get offset, hours and positivity
-				offset, _ := strconv.Atoi(match[19:21])
-				sign := string(match[18])
-
-				//Give it a minus if there is minus
-				if sign == "-" {
-					offset = -offset
-				}
-
-				//Check for valid offset
-				if !validOffset(offset) {
-					return match
-				}
-			}
-
-			//Replace the month
-			if monthName, exists := monthMap[month]; exists {
-				month = monthName
-			}
-			return day + " " + month + " " + year
-
-		}
-
-		//T12 format - converts into HH-MM(AM/PM) (+OO:00)
-		if match[0:3] == "T12" {
-			hours, _ := strconv.Atoi(match[15:17])
-			minutes := match[18:20]
-			sign := string(match[20])
-
-			//Detect not Zulu offset
-			if len(match) > 22 {
-				//Get offset
-				offset, _ := strconv.Atoi(match[21:23])
-
-				//Give it a minus if there is minus
-				if sign == "-" {
-					offset = -offset
-				}
-
-				//Check for valid offset
-				if !validOffset(offset) {
-					return match
-				}
-				outputOffset := prefixZero(offset)
-
-				if hours > 13 && hours < 24 {
-					hours -= 12
-					if hours < 0 {
-						hours = -hours
-					}
-					return prefixZero(hours) + ":" + minutes + "PM (" + outputOffset + ":00)"
-				} else if hours == 24 || hours == 0 {
-					hours = 12
-				}
-				return prefixZero(hours) + ":" + minutes + "AM (" + outputOffset + ":00)"
-			} else { //Zulu offset
-				if hours > 13 && hours < 24 {
-					hours -= 12
-					if hours < 0 {
-						hours = -hours
-					}
-					return prefixZero(hours) + ":" + minutes + "PM (+00:00)"
-				} else if hours == 24 || hours == 0 {
-					hours = 12
-				}
-				return prefixZero(hours) + ":" + minutes + "AM (+00:00)"
-			}
-		}
+	return placeTimeTokens(input, timeText)
+}
 
-		if match[0:3] == "T24" {
-			//T24 format - converts into HH-MM (+OO:00)
-			hours, _ := strconv.Atoi(match[15:17])
-			minutes := match[18:20]
-			sign := string(match[20])
-			//Detect not Zulu offset
-			if len(match) > 22 {
-				//Get offset
-				offset, _ := strconv.Atoi(match[21:23])
-
-				//Give it a minus if there is minus
-				if sign == "-" {
-					offset = -offset
-				}
-
-				// Check for valid offset
-				if !validOffset(offset) {
-					return match
-				}
-				outputOffset := prefixZero(offset)
-
-				return prefixZero(hours) + ":" + minutes + " (" + outputOffset + ":00)"
-			} else { //Zulu offset
-				return prefixZero(hours) + ":" + minutes + " (+00:00)"
-			}
-		}
-		return match
-	})
+// A date or time of the text output, with its day shift
+func timeText(stamp timeStamp, text string, shift int) string {
+	if shift != 0 {
+		return text + " " + formatDayShift(shift)
+	}
+	return text
 }
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Rewrites the golden files with the current output: go test -run Golden -update
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata/golden")
+
+// A two airport lookup, so the goldens don't change with the bundled data
+const goldenLookup = "testdata/lookups/small.csv"
+
+// Three airlines and a row without a name
+const testAirlines = "testdata/lookups/airlines.csv"
+
+func loadTestLookup(t testing.TB, lookupPaths ...string) {
+	t.Helper()
+	if err := loadAirports(lookupPaths...); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadAirlines(testAirlines); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics = nil
+}
+
+// Converts markup as the convert command does, through the itinerary and the renderer of format
+func convertMarkup(t testing.TB, input string, format string) string {
+	t.Helper()
+	read, err := parseItinerary("itinerary.txt", input, bookingNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rendererFor(format, bookingNow).render(read)
+}
+
+// Compares got with testdata/golden/name, or writes it there with -update
+func checkGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run go test -run Golden -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %v\ngot:\n%v\nwant:\n%v", path, got, want)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	inputs, err := filepath.Glob(filepath.Join("testdata", "itineraries", "*.txt"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no itineraries in testdata/itineraries: %v", err)
+	}
+	for _, inputPath := range inputs {
+		name := strings.TrimSuffix(filepath.Base(inputPath), ".txt")
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(inputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name+".txt", convertMarkup(t, string(input), "txt"))
+			checkGolden(t, name+".html", convertMarkup(t, string(input), "html"))
+		})
+	}
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// The text of the itinerary part of the HTML output, as the text output would write it
+func htmlText(html string) string {
+	_, body, _ := strings.Cut(html, `<td class="content" style="padding:10px 30px; text-align: left; font-size: 16px; color: #333333;"><p>`)
+	body, _, _ = strings.Cut(body, `<p style="text-align: center;">`)
+	body = strings.ReplaceAll(body, "</p><p>", "\n")
+	body = strings.ReplaceAll(body, "<sup>", " ")
+	return strings.TrimRight(htmlTag.ReplaceAllString(body, ""), "\n")
+}
+
+// Both outputs replace the same codes, the HTML only adds links and emphasis
+func TestHTMLMatchesText(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	inputs, _ := filepath.Glob(filepath.Join("testdata", "itineraries", "*.txt"))
+	for _, inputPath := range inputs {
+		input, err := os.ReadFile(inputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := convertMarkup(t, string(input), "txt")
+		if got := htmlText(convertMarkup(t, string(input), "html")); got != text {
+			t.Errorf("%v: the HTML reads\n%v\nthe text output\n%v", inputPath, got, text)
+		}
+	}
+}
+
+func TestPlaceTimes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"date", "D(2023-06-15T14:00-07:00)", "15 Jun 2023"},
+		{"date zulu", "D(2023-06-15T14:00Z)", "15 Jun 2023"},
+		{"midnight 12h", "T12(2023-06-15T00:00Z)", "12:00AM (+00:00)"},
+		{"midnight 24h", "T24(2023-06-15T00:00Z)", "00:00 (+00:00)"},
+		{"afternoon 12h", "T12(2023-06-15T14:05-07:00)", "02:05PM (-07:00)"},
+		{"zulu 24h", "T24(2023-06-15T14:00Z)", "14:00 (+00:00)"},
+		{"westmost offset", "T24(2023-06-15T14:00-12:00)", "14:00 (-12:00)"},
+		{"eastmost offset", "T24(2023-06-15T14:00+14:00)", "14:00 (+14:00)"},
+		{"noon 12h", "T12(2023-06-15T12:00Z)", "12:00PM (+00:00)"},
+		{"one 12h", "T12(2023-06-15T13:00+01:00)", "01:00PM (+01:00)"},
+		{"minutes in offset", "T24(2023-06-15T14:00+05:30)", "14:00 (+05:30)"},
+		{"minus sign", "T24(2023-06-15T14:00−05:00)", "14:00 (-05:00)"},
+		{"impossible date", "D(2023-02-30T14:00Z)", "D(2023-02-30T14:00Z)"},
+		{"offset below range", "T24(2023-06-15T14:00-13:00)", "T24(2023-06-15T14:00-13:00)"},
+		{"offset above range", "D(2023-06-15T14:00+15:00)", "D(2023-06-15T14:00+15:00)"},
+		{"missing offset", "T24(2023-06-15T14:00)", "T24(2023-06-15T14:00)"},
+		{"short month", "D(2023-6-15T14:00Z)", "D(2023-6-15T14:00Z)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := placeTimes(test.input); got != test.want {
+				t.Errorf("placeTimes(%q) = %q, want %q", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCodeBoundaries(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"#LHR", "London Heathrow Airport"},
+		{"##EGLL", "London Heathrow Airport"},
+		{"(#LHR)", "(London Heathrow Airport)"},
+		{"#LHRX", "#LHRX"},
+		{"A#LHR", "A#LHR"},
+		{"##EGLLX", "##EGLLX"},
+		{"B##EGLL", "B##EGLL"},
+		{"#lhr", "#lhr"},
+		{"#ZZZ", "#ZZZ"},
+		{"*#LHR, ", "London,"},
+		{"*#LHR", "London"},
+		{"*##EGLL", "London"},
+	}
+	for _, test := range tests {
+		if got := convertMarkup(t, test.input, "txt"); got != test.want {
+			t.Errorf("converted %q to %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	//City, country and distance tokens repeat a stop, they don't add legs
+	read := itineraryFromMarkup("From #LAX to ##EGLL, *#LAX ^#LAX ^^##EGLL *##EGLL DIST(#LAX,#LHR) A#LAX #LAXX ###LAX\nBack to #LAX")
+	var got []string
+	for _, airport := range read.route() {
+		got = append(got, airport.IATA_Code)
+	}
+	if strings.Join(got, " ") != "LAX LHR LAX" {
+		t.Errorf("route = %v, want LAX LHR LAX", got)
+	}
+	if got := formatDistance(read.totalDistance()); got != "17519 km" {
+		t.Errorf("total distance = %v, want two legs of 8760 km", got)
+	}
+}
+
+func TestLineBreaks(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a\r\nb", "a\nb"},
+		{"a\rb\vc\fd", "a\nb\nc\nd"},
+		{"a\n\n\n\n\nb", "a\n\nb"},
+		{"\r\n\r\n\r\na\r\n\r\n\r\nb\r\n", "a\n\nb"},
+		{"  a  \n", "a"},
+	}
+	for _, test := range tests {
+		if got := cleanUpDoubleWhiteSpaces(replaceLineBreaks(test.input)); got != test.want {
+			t.Errorf("line breaks of %q = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	defer func() {
+		duplicatePolicy = "first"
+		diagnostics = nil
+	}()
+	tests := []struct {
+		path       string
+		duplicates string
+		want       error
+	}{
+		{"testdata/lookups/does-not-exist.csv", "first", errLookupNotFound},
+		{"testdata/lookups/empty.csv", "first", errLookupMalformed},
+		{"testdata/lookups/missing-column.csv", "first", errLookupMalformed},
+		{"testdata/lookups/broken.json", "first", errLookupMalformed},
+		{"testdata/lookups/conflict.csv", "error", errLookupMalformed},
+		{"testdata/lookups/conflict.csv", "first", nil},
+	}
+	for _, test := range tests {
+		duplicatePolicy = test.duplicates
+		err := loadAirports(test.path)
+		if !errors.Is(err, test.want) {
+			t.Errorf("loadAirports(%v) with --duplicates %v = %v, want %v", test.path, test.duplicates, err, test.want)
+		}
+		var fileErr *lookupFileError
+		if err != nil && (!errors.As(err, &fileErr) || fileErr.path != test.path) {
+			t.Errorf("loadAirports(%v) = %v, the error doesn't name the lookup", test.path, err)
+		}
+	}
+}
+
+func TestReloadAirports(t *testing.T) {
+	defer func() { diagnostics = nil }()
+	loadTestLookup(t, embeddedLookupPath)
+
+	//A second load replaces the airports instead of adding to them
+	if err := loadAirports(goldenLookup); err != nil {
+		t.Fatal(err)
+	}
+	if len(airports) != 2 {
+		t.Errorf("reloading with %v kept %d airports, want 2", goldenLookup, len(airports))
+	}
+	//A lookup that fails leaves the loaded ones alone
+	if err := loadAirports(goldenLookup, "testdata/lookups/empty.csv"); err == nil {
+		t.Fatal("loading empty.csv gave no error")
+	}
+	if len(airports) != 2 {
+		t.Errorf("a failed load left %d airports, want 2", len(airports))
+	}
+}
+
+func FuzzPlaceTimes(f *testing.F) {
+	f.Add("D(2023-06-15T14:00-07:00)")
+	f.Add("T12(2023-06-15T00:00Z)")
+	f.Add("T24(2023-06-15T14:00+14:00)")
+	f.Add("T24(2023-06-15T14:00−05:00)")
+	f.Add("T12(9999-99-99T99:99+99:00)")
+	f.Fuzz(func(t *testing.T, input string) {
+		placeTimes(input)
+	})
+}
+
+func FuzzConvert(f *testing.F) {
+	loadTestLookup(f, goldenLookup)
+	inputs, _ := filepath.Glob(filepath.Join("testdata", "itineraries", "*.txt"))
+	for _, inputPath := range inputs {
+		if input, err := os.ReadFile(inputPath); err == nil {
+			f.Add(string(input))
+		}
+	}
+	f.Add("*#LHR")
+	f.Add("^^##EGLL DIST(#LHR,#LAX) DIST(TOTAL)")
+	f.Fuzz(func(t *testing.T, input string) {
+		read, err := parseItinerary("itinerary.txt", input, bookingNow)
+		if err != nil {
+			return
+		}
+		for _, format := range []string{"txt", "html", "json", "ics"} {
+			rendererFor(format, bookingNow).render(read)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Bumped whenever the Airport struct or the validation changes
+const indexVersion = 1
+
+// Next to the lookup it was built from
+const indexExtension = ".idx"
+
+// A lookup compiled by index build, checked against its source before use
+type airportIndex struct {
+	Version  int
+	Checksum [sha256.Size]byte
+	Settings string
+	Airports []Airport
+	Warnings []Diagnostic
+}
+
+func indexPath(lookupPath string) string {
+	return lookupPath + indexExtension
+}
+
+// Everything that changes how a lookup is read has to match for an index to be used
+func lookupSettings(lookupPath string, content string) string {
+	return fmt.Sprintf("format=%v coords=%v duplicates=%v columns=%v",
+		detectLookupFormat(lookupPath, content), coordinateOrder, duplicatePolicy, columnMapping)
+}
+
+func buildIndex(lookupPath string) (airportIndex, error) {
+	content, err := readLookupFile(lookupPath)
+	if err != nil {
+		return airportIndex{}, fmt.Errorf("%w: %v", errLookupNotFound, err)
+	}
+
+	//The warnings of the CSV are kept so a run from the index reports the same
+	before := len(diagnostics)
+	layer, err := parseLookupFile(lookupPath)
+	if err != nil {
+		return airportIndex{}, err
+	}
+	warnings := append([]Diagnostic(nil), diagnostics[before:]...)
+
+	//The source is filled in again with the path the index is loaded through
+	for i := range layer {
+		layer[i].Source = ""
+	}
+
+	return airportIndex{
+		Version:  indexVersion,
+		Checksum: sha256.Sum256([]byte(content)),
+		Settings: lookupSettings(lookupPath, content),
+		Airports: layer,
+		Warnings: warnings,
+	}, nil
+}
+
+func writeIndex(path string, index airportIndex) error {
+	var encoded bytes.Buffer
+	if err := gob.NewEncoder(&encoded).Encode(index); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, encoded.Bytes())
+}
+
+var errIndexStale = errors.New("index is stale")
+
+// Returns the airports of the index of a lookup, or errIndexStale when the lookup changed since
+func loadIndex(lookupPath string) ([]Airport, error) {
+	encoded, err := os.ReadFile(indexPath(lookupPath))
+	if err != nil {
+		return nil, err
+	}
+	content, err := readLookupFile(lookupPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errLookupNotFound, err)
+	}
+
+	var index airportIndex
+	if err := gob.NewDecoder(bytes.NewReader(encoded)).Decode(&index); err != nil {
+		return nil, fmt.Errorf("%w: %v", errIndexStale, err)
+	}
+	if index.Version != indexVersion || index.Checksum != sha256.Sum256([]byte(content)) ||
+		index.Settings != lookupSettings(lookupPath, content) {
+		return nil, errIndexStale
+	}
+
+	diagnostics = append(diagnostics, index.Warnings...)
+	for i := range index.Airports {
+		index.Airports[i].Source = lookupPath
+	}
+	return index.Airports, nil
+}
+
+// The flags of the lookup the index is built from and --diagnostics for its warnings
+func registerIndexFlags(flags *flag.FlagSet) {
+	addLookupFlags(flags)
+	flags.StringVar(&diagnosticsFormat, "diagnostics", "text", "Format of the lookup warnings: text or json")
+}
+
+func runIndexBuild(args []string) int {
+	buildFlags := newFlagSet("index build")
+	registerIndexFlags(buildFlags)
+	if err := parseFlags(buildFlags, args); err != nil {
+		return usageExit(err)
+	}
+	lookups := chooseLookups(buildFlags.Args())
+	if len(lookups) != 1 || lookups[0] == embeddedLookupPath {
+		buildFlags.Usage()
+		return exitUsage
+	}
+
+	defer flushDiagnostics()
+
+	if err := checkDiagnosticsFormat(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return exitUsage
+	}
+	if err := checkLookupFlags(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return exitUsage
+	}
+
+	lookupPath := lookups[0]
+	index, err := buildIndex(lookupPath)
+	if err != nil {
+		return reportLookupError(lookupPath, err)
+	}
+
+	path := indexPath(lookupPath)
+	if err := writeIndex(path, index); err != nil {
+		report(Diagnostic{Code: diagOutputFailed, Severity: severityError, File: path, Message: "error writing index: " + err.Error()})
+		return exitOutput
+	}
+	if diagnosticsFormat == "text" {
+		fmt.Printf("%v written with %d airports\n", path, len(index.Airports))
+	}
+	return exitOK
+}
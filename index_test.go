@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const benchLookup = "airport-lookup.csv"
+
+// Copies the bundled lookup into a temporary directory so the index is written there
+func tempLookup(tb testing.TB) string {
+	content, err := os.ReadFile(benchLookup)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	path := filepath.Join(tb.TempDir(), benchLookup)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+func buildTempIndex(tb testing.TB, lookupPath string) {
+	index, err := buildIndex(lookupPath)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := writeIndex(indexPath(lookupPath), index); err != nil {
+		tb.Fatal(err)
+	}
+	diagnostics = nil
+}
+
+func TestIndexMatchesLookup(t *testing.T) {
+	lookupPath := tempLookup(t)
+	fromCSV, err := parseLookupFile(lookupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := len(diagnostics)
+	buildTempIndex(t, lookupPath)
+
+	fromIndex, err := loadIndex(lookupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fromIndex) != len(fromCSV) || fromIndex[0] != fromCSV[0] || fromIndex[len(fromIndex)-1] != fromCSV[len(fromCSV)-1] {
+		t.Errorf("index has %d airports, the lookup %d", len(fromIndex), len(fromCSV))
+	}
+	if len(diagnostics) != warnings {
+		t.Errorf("index reported %d warnings, the lookup %d", len(diagnostics), warnings)
+	}
+	diagnostics = nil
+}
+
+func TestStaleIndexFallsBack(t *testing.T) {
+	lookupPath := tempLookup(t)
+	buildTempIndex(t, lookupPath)
+
+	//A changed lookup or other flags both make the index stale
+	file, err := os.OpenFile(lookupPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("\nTest Airport,GB,Test,EGZZ,ZZZ,\"0, 51\"\n")
+	file.Close()
+	if _, err := loadIndex(lookupPath); err != errIndexStale {
+		t.Errorf("changed lookup: got %v, want %v", err, errIndexStale)
+	}
+
+	buildTempIndex(t, lookupPath)
+	duplicatePolicy = "last"
+	defer func() { duplicatePolicy = "first" }()
+	if _, err := loadIndex(lookupPath); err != errIndexStale {
+		t.Errorf("changed flags: got %v, want %v", err, errIndexStale)
+	}
+
+	layer, err := loadLookupFile(lookupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, airport := range layer {
+		found = found || airport.IATA_Code == "ZZZ"
+	}
+	if !found || diagnostics[0].Code != diagIndexStale {
+		t.Errorf("stale index was not replaced by the lookup")
+	}
+	diagnostics = nil
+}
+
+// The way the lookup was read before any validation, for comparison
+func BenchmarkCSVReadAll(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		file, err := os.Open(benchLookup)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := csv.NewReader(file).ReadAll(); err != nil {
+			b.Fatal(err)
+		}
+		file.Close()
+	}
+}
+
+func BenchmarkLoadLookupCSV(b *testing.B) {
+	lookupPath := tempLookup(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := loadLookupFile(lookupPath); err != nil {
+			b.Fatal(err)
+		}
+		diagnostics = nil
+	}
+}
+
+func BenchmarkLoadLookupIndex(b *testing.B) {
+	lookupPath := tempLookup(b)
+	buildTempIndex(b, lookupPath)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := loadLookupFile(lookupPath); err != nil {
+			b.Fatal(err)
+		}
+		diagnostics = nil
+	}
+}
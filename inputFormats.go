@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// Set with --input-format
+var inputFormat = "auto"
+
+// Formats an itinerary may come in, text is the #LAX and D(...) markup itself
+var inputFormats = []string{"auto", "text", "pnr", "json"}
+
+// A line an adapter could not read, the rest of the booking is still converted
+type inputProblem struct {
+	line    int
+	message string
+}
+
+// Reads one structured format into an itinerary, now settles the year PNR dates leave out
+type inputAdapter interface {
+	readItinerary(content string, now time.Time) (Itinerary, []inputProblem, error)
+}
+
+var inputAdapters = map[string]inputAdapter{
+	"pnr":  pnrAdapter{},
+	"json": jsonBookingAdapter{},
+}
+
+func addInputFlags(flags *flag.FlagSet) {
+	flags.StringVar(&inputFormat, "input-format", "auto", "Format of the itinerary: auto, text, pnr or json")
+}
+
+func checkInputFormat() error {
+	if !containsWord(inputFormats, inputFormat) {
+		return fmt.Errorf("unknown input format %v, expected %v", inputFormat, strings.Join(inputFormats, ", "))
+	}
+	return nil
+}
+
+func detectInputFormat(path string, content string) string {
+	if inputFormat != "auto" {
+		return inputFormat
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".pnr":
+		return "pnr"
+	}
+
+	//A markup itinerary may well have a line that reads like a segment, only the header
+	//of an Amadeus dump makes it a PNR
+	if pnrHeader.MatchString(strings.TrimSpace(replaceLineBreaks(content))) {
+		return "pnr"
+	}
+	return "text"
+}
+
+// Airline codes are two characters for IATA and three letters for ICAO
+func airlineMarkup(code string) (string, bool) {
+	if airlineIATAShape.MatchString(code) && airlineCodeShape(code) {
+		return "@" + code, true
+	}
+	if airlineICAOShape.MatchString(code) {
+		return "@@" + code, true
+	}
+	return "", false
+}
+
+func airportMarkup(code string) (string, bool) {
+	if iataShape.MatchString(code) {
+		return "#" + code, true
+	}
+	if icaoShape.MatchString(code) {
+		return "##" + code, true
+	}
+	return "", false
+}
+
+// The record locator line an Amadeus PNR dump starts with, "RP/LONBA0100/LONBA0100"
+var pnrHeader = regexp.MustCompile(`^RP/[A-Z0-9]+`)
+
+// Amadeus and Sabre style segment lines, "1 BA 283 Y 15JUN 4 LAXLHR HK1 1400 0830+1"
+var pnrSegment = regexp.MustCompile(`^\s*\d{1,2}\s+([A-Z0-9]{2})\s*(\d{1,4})\s*([A-Z])\s+(\d{2})([A-Z]{3})\s+(?:[1-7A-Z]\s+)?([A-Z]{3})([A-Z]{3})\s+([A-Z]{2})\d{1,2}\s+(\d{4})\s+(\d{4})([+-]\d)?(?:\s|$)`)
+
+// Lines that start like a segment, reported when they don't read as one
+var pnrSegmentStart = regexp.MustCompile(`^\s*\d{1,2}\s+[A-Z0-9]{2}\s*\d{1,4}\s*[A-Z]\s+\d{2}[A-Z]{3}`)
+
+// Passenger names, "1.1SMITH/JOHN MR 2.1SMITH/JANE MRS"
+var pnrName = regexp.MustCompile(`\d+\.\d+([A-Z'-]+(?: [A-Z'-]+)*)/([A-Z'-]+(?: [A-Z'-]+)*)`)
+
+var pnrTitles = []string{"MR", "MRS", "MS", "MISS", "MSTR", "DR"}
+
+// What the status codes of a segment mean to a customer
+var pnrStatuses = map[string]string{
+	"HK": "confirmed",
+	"KK": "confirmed",
+	"RR": "reconfirmed",
+	"TK": "schedule changed",
+	"HL": "waitlisted",
+	"HN": "requested",
+	"UC": "not confirmed",
+	"UN": "not operating",
+	"HX": "cancelled",
+}
+
+var pnrMonths = map[string]time.Month{
+	"JAN": time.January, "FEB": time.February, "MAR": time.March, "APR": time.April,
+	"MAY": time.May, "JUN": time.June, "JUL": time.July, "AUG": time.August,
+	"SEP": time.September, "OCT": time.October, "NOV": time.November, "DEC": time.December,
+}
+
+// A PNR dump as Amadeus or Sabre print it, passengers and air segments are read, other lines skipped
+type pnrAdapter struct{}
+
+func (pnrAdapter) readItinerary(content string, now time.Time) (Itinerary, []inputProblem, error) {
+	var read Itinerary
+	var problems []inputProblem
+	title := cases.Title(language.Und)
+
+	//Dates have no year, each one is the first on or after the one before
+	after := now.AddDate(0, 0, -30)
+	for i, line := range strings.Split(replaceLineBreaks(content), "\n") {
+		line = strings.ToUpper(line)
+		for _, name := range pnrName.FindAllStringSubmatch(line, -1) {
+			first := strings.Fields(name[2])
+			if len(first) > 1 && containsWord(pnrTitles, first[len(first)-1]) {
+				first = first[:len(first)-1]
+			}
+			read.Passengers = append(read.Passengers, title.String(strings.Join(first, " ")+" "+name[1]))
+		}
+
+		match := pnrSegment.FindStringSubmatch(line)
+		if match == nil {
+			if pnrSegmentStart.MatchString(line) {
+				problems = append(problems, inputProblem{i + 1, "unreadable PNR segment, expected 1 BA 283 Y 15JUN LAXLHR HK1 1400 0830+1"})
+			}
+			continue
+		}
+		segment, err := pnrFlight(match, after)
+		if err != nil {
+			problems = append(problems, inputProblem{i + 1, err.Error()})
+			continue
+		}
+		segment.Line = i + 1
+		after = segment.Departure
+		read.Segments = append(read.Segments, segment)
+	}
+	return read, problems, nil
+}
+
+func pnrFlight(match []string, after time.Time) (Segment, error) {
+	day, _ := strconv.Atoi(match[4])
+	month, found := pnrMonths[match[5]]
+	if !found {
+		return Segment{}, fmt.Errorf("unknown month %v", match[5])
+	}
+	date, err := nextDate(day, month, after)
+	if err != nil {
+		return Segment{}, err
+	}
+	departure, err := clockOn(date, match[9])
+	if err != nil {
+		return Segment{}, err
+	}
+	arrival, err := clockOn(date, match[10])
+	if err != nil {
+		return Segment{}, err
+	}
+	shift, _ := strconv.Atoi(match[11])
+
+	airline, valid := airlineMarkup(match[1])
+	if !valid {
+		return Segment{}, fmt.Errorf("invalid airline code %v", match[1])
+	}
+	status := pnrStatuses[match[8]]
+	if status == "" {
+		status = "status " + match[8]
+	}
+	return Segment{
+		Airline:   airline,
+		Number:    match[2],
+		Class:     match[3],
+		From:      "#" + match[6],
+		To:        "#" + match[7],
+		Departure: departure,
+		Arrival:   arrival.AddDate(0, 0, shift),
+		LocalOnly: true,
+		DayShift:  shift,
+		Status:    status,
+	}, nil
+}
+
+// The first day and month on or after a date, a 29FEB looks up to the next leap year
+func nextDate(day int, month time.Month, after time.Time) (time.Time, error) {
+	from := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, time.UTC)
+	for year := after.Year(); year <= after.Year()+4; year++ {
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		if date.Day() == day && !date.Before(from) {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %02d%v", day, strings.ToUpper(month.String()[:3]))
+}
+
+// 1400 on a date
+func clockOn(date time.Time, clock string) (time.Time, error) {
+	hours, _ := strconv.Atoi(clock[:2])
+	minutes, _ := strconv.Atoi(clock[2:])
+	if hours > 23 || minutes > 59 {
+		return time.Time{}, fmt.Errorf("invalid time %v", clock)
+	}
+	return date.Add(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute), nil
+}
+
+// A booking exported as JSON
+type jsonBookingAdapter struct{}
+
+type jsonBooking struct {
+	Reference  string        `json:"reference"`
+	Passengers []string      `json:"passengers"`
+	Segments   []jsonSegment `json:"segments"`
+}
+
+type jsonSegment struct {
+	Flight    string `json:"flight"` //BA283 or BAW283
+	Class     string `json:"class"`
+	From      string `json:"from"` //LAX or KLAX
+	To        string `json:"to"`
+	Departure string `json:"departure"` //2023-06-15T14:00-07:00
+	Arrival   string `json:"arrival"`
+	Status    string `json:"status"`
+}
+
+// ISO 8601 with or without seconds, a time without an offset is local
+var jsonTimeLayouts = []string{"2006-01-02T15:04Z07:00", time.RFC3339, "2006-01-02T15:04", "2006-01-02T15:04:05"}
+
+func (jsonBookingAdapter) readItinerary(content string, now time.Time) (Itinerary, []inputProblem, error) {
+	var parsed jsonBooking
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		return Itinerary{}, nil, fmt.Errorf("error reading JSON booking: %v", err)
+	}
+
+	read := Itinerary{Reference: parsed.Reference, Passengers: parsed.Passengers}
+	var problems []inputProblem
+	for i, raw := range parsed.Segments {
+		segment, err := jsonFlight(raw)
+		if err != nil {
+			problems = append(problems, inputProblem{0, fmt.Sprintf("segment %d: %v", i+1, err)})
+			continue
+		}
+		read.Segments = append(read.Segments, segment)
+	}
+	return read, problems, nil
+}
+
+func jsonFlight(raw jsonSegment) (Segment, error) {
+	flight := strings.ToUpper(strings.ReplaceAll(raw.Flight, " ", ""))
+	code, number := "", ""
+	//The number starts after two characters, or three letters for an ICAO code
+	if len(flight) > 3 && strings.Trim(flight[:3], "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == "" {
+		code, number = flight[:3], flight[3:]
+	} else if len(flight) > 2 {
+		code, number = flight[:2], flight[2:]
+	}
+	airline, validAirline := airlineMarkup(code)
+	if _, err := strconv.Atoi(number); !validAirline || err != nil || len(number) > 4 {
+		return Segment{}, fmt.Errorf("invalid flight %q, expected BA283 or BAW283", raw.Flight)
+	}
+
+	from, validFrom := airportMarkup(strings.ToUpper(raw.From))
+	to, validTo := airportMarkup(strings.ToUpper(raw.To))
+	if !validFrom || !validTo {
+		return Segment{}, fmt.Errorf("invalid airport %q or %q, expected an IATA or ICAO code", raw.From, raw.To)
+	}
+
+	departure, departureLocal, err := parseBookingTime(raw.Departure)
+	if err != nil {
+		return Segment{}, err
+	}
+	arrival, arrivalLocal, err := parseBookingTime(raw.Arrival)
+	if err != nil {
+		return Segment{}, err
+	}
+
+	segment := Segment{
+		Airline:   airline,
+		Number:    number,
+		Class:     raw.Class,
+		From:      from,
+		To:        to,
+		Departure: departure,
+		Arrival:   arrival,
+		Status:    raw.Status,
+		DayShift:  calendarDays(departure, arrival),
+	}
+	//Without both offsets the times are written as they are
+	segment.LocalOnly = departureLocal || arrivalLocal
+	return segment, nil
+}
+
+func parseBookingTime(value string) (time.Time, bool, error) {
+	for i, layout := range jsonTimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			_, seconds := parsed.Zone()
+			if i < 2 && !validOffset(seconds/60) {
+				return time.Time{}, false, fmt.Errorf("offset out of range in %v, it must be between -12:00 and +14:00", value)
+			}
+			return parsed, i >= 2, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("invalid time %q, expected 2023-06-15T14:00-07:00", value)
+}
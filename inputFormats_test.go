@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// PNR dates have no year, they are read as the next ones after this day
+var bookingNow = time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+func TestBookingGolden(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	defer func() { diagnostics = nil }()
+	inputs, err := filepath.Glob(filepath.Join("testdata", "bookings", "*"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no bookings in testdata/bookings: %v", err)
+	}
+	for _, inputPath := range inputs {
+		name := filepath.Base(inputPath)
+		t.Run(name, func(t *testing.T) {
+			content, err := os.ReadFile(inputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			read, err := parseItinerary(inputPath, string(content), bookingNow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name+".txt", textRenderer{}.render(read))
+			checkGolden(t, name+".html", htmlRenderer{}.render(read))
+		})
+	}
+}
+
+func TestPNRSegments(t *testing.T) {
+	read, problems, err := pnrAdapter{}.readItinerary(`  1.1SMITH/JOHN MR   2.1O'BRIEN/MARY ANN MS
+  2  BA 283 Y 15JUN 4 LAXLHR HK2  1400 0830+1
+  3  AY1331 Y 18JUN LHRHEL UN1 0920 1405
+  4  BA 284 Y 02JAN 2 LHRLAX HK2  1015 1325
+  5  BA 285 Y 31JUN 2 LHRLAX HK2  1015 1325
+  6  BA 286 Y 01JUL 2 LHRLAX HK2  2515 1325`, bookingNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(read.Passengers, ", ") != "John Smith, Mary Ann O'brien" {
+		t.Errorf("passengers = %q", read.Passengers)
+	}
+	if len(read.Segments) != 3 || len(problems) != 2 {
+		t.Fatalf("read %d segments and %d problems, want 3 and 2: %v", len(read.Segments), len(problems), problems)
+	}
+
+	first := read.Segments[0]
+	if first.Airline != "@BA" || first.Number != "283" || first.From != "#LAX" || first.To != "#LHR" || first.Status != "confirmed" {
+		t.Errorf("first segment = %+v", first)
+	}
+	if first.Departure != time.Date(2023, 6, 15, 14, 0, 0, 0, time.UTC) || first.Arrival != time.Date(2023, 6, 16, 8, 30, 0, 0, time.UTC) || first.DayShift != 1 {
+		t.Errorf("first segment runs %v to %v with shift %d", first.Departure, first.Arrival, first.DayShift)
+	}
+	if read.Segments[1].Status != "not operating" {
+		t.Errorf("UN status = %q", read.Segments[1].Status)
+	}
+	//January comes after June, so it is the next year
+	if read.Segments[2].Departure.Year() != 2024 {
+		t.Errorf("02JAN after 18JUN is read as %v", read.Segments[2].Departure)
+	}
+	if problems[0].line != 5 || problems[1].line != 6 {
+		t.Errorf("problems on lines %d and %d, want 5 and 6", problems[0].line, problems[1].line)
+	}
+}
+
+func TestDetectInputFormat(t *testing.T) {
+	defer func() { inputFormat = "auto" }()
+	tests := []struct {
+		format  string
+		path    string
+		content string
+		want    string
+	}{
+		{"auto", "trip.json", "{}", "json"},
+		{"auto", "trip.PNR", "", "pnr"},
+		{"auto", "trip.txt", "RP/LONBA0100\n 1 BA 283 Y 15JUN 4 LAXLHR HK1 1400 0830+1\n", "pnr"},
+		{"auto", "trip.txt", "\n\nRP/LONBA0100\n 1 BA 283 Y 15JUN 4 LAXLHR HK1 1400 0830+1\n", "pnr"},
+		{"auto", "trip.txt", "Flight from #LAX at T24(2023-06-15T14:00-07:00)", "text"},
+		{"auto", "trip.txt", "Your flights:\n 1 BA 283 Y 15JUN 4 LAXLHR HK1 1400 0830+1\n", "text"},
+		{"auto", "trip.txt", "Flights RP/LONBA0100", "text"},
+		{"text", "trip.json", "{}", "text"},
+		{"pnr", "trip.txt", "", "pnr"},
+	}
+	for _, test := range tests {
+		inputFormat = test.format
+		if got := detectInputFormat(test.path, test.content); got != test.want {
+			t.Errorf("detectInputFormat(%v) with --input-format %v = %v, want %v", test.path, test.format, got, test.want)
+		}
+	}
+}
+
+func TestParseItineraryErrors(t *testing.T) {
+	defer func() { diagnostics = nil }()
+	for path, content := range map[string]string{
+		"broken.json": `{"segments": [`,
+		"empty.json":  `{"segments": []}`,
+		"notes.pnr":   "RP/LONBA0100\n  1.1SMITH/JOHN MR\n",
+	} {
+		if _, err := parseItinerary(path, content, bookingNow); err == nil {
+			t.Errorf("parseItinerary(%v) gave no error", path)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// A trip as every input is read into and every output is written from
+type Itinerary struct {
+	Reference  string
+	Passengers []string
+	Segments   []Segment
+	Notes      []Note
+}
+
+// A paragraph of the markup that is not a flight
+type Note struct {
+	Text  string //As written, tokens and all
+	Line  int
+	After int //Segments written before the note, so the outputs keep the order of the input
+}
+
+// One flight of an itinerary
+type Segment struct {
+	Airline     string //@BA or @@BAW, empty when the markup names none
+	Number      string
+	Class       string
+	Status      string
+	From, To    string //#LAX or ##KLAX
+	Origin      Airport
+	Destination Airport
+	Departure   time.Time
+	Arrival     time.Time //Zero when the markup gives only one time
+	LocalOnly   bool      //PNR times have no offset, so they can't become tokens
+	DayShift    int       //Days the arrival lands after the departure, 0830+1
+	Line        int       //Where the segment starts in the input, 0 when unknown
+	Text        string    //The paragraph of the markup it was read from, empty for a booking
+}
+
+// Airport codes the converters replace, DIST(...) is read on its own
+var airportToken = regexp.MustCompile(`##[A-Z]{4}|#[A-Z]{3}`)
+
+// Reads any input into an itinerary, PNR dumps and JSON bookings go through their adapter
+func parseItinerary(path string, content string, now time.Time) (Itinerary, error) {
+	format := detectInputFormat(path, content)
+	adapter, found := inputAdapters[format]
+	if !found {
+		return itineraryFromMarkup(content), nil
+	}
+	read, problems, err := adapter.readItinerary(content, now)
+	if err != nil {
+		return Itinerary{}, err
+	}
+	for _, problem := range problems {
+		problemAt := Diagnostic{Code: diagInputMalformed, Severity: severityWarning, File: path, Message: problem.message}
+		if problem.line > 0 {
+			problemAt.Line, problemAt.Column = problem.line, 1
+		}
+		report(problemAt)
+	}
+	if len(read.Segments) == 0 {
+		return Itinerary{}, fmt.Errorf("no flight segments found in the %v booking", format)
+	}
+	for i := range read.Segments {
+		resolveSegment(&read.Segments[i])
+	}
+	return read, nil
+}
+
+// A paragraph with two airports and a time is a flight, any other paragraph a note.
+// Both keep their text, the txt and html outputs prettify what was written
+func itineraryFromMarkup(content string) Itinerary {
+	var read Itinerary
+	normalized := replaceLineBreaks(content)
+
+	start := 0
+	breaks := append(paragraphBreak.FindAllStringIndex(normalized, -1), []int{len(normalized), len(normalized)})
+	for _, loc := range breaks {
+		paragraph := normalized[start:loc[0]]
+		blank := paragraph[:len(paragraph)-len(strings.TrimLeft(paragraph, " \t\n"))]
+		line := strings.Count(normalized[:start], "\n") + strings.Count(blank, "\n") + 1
+		start = loc[1]
+
+		//The text starts on its first line, indented as written
+		text := strings.TrimRight(paragraph[strings.LastIndex(blank, "\n")+1:], " \t\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if segment, found := segmentFromParagraph(paragraph); found {
+			segment.Line, segment.Text = line, text
+			resolveSegment(&segment)
+			read.Segments = append(read.Segments, segment)
+		} else {
+			read.Notes = append(read.Notes, Note{Text: text, Line: line, After: len(read.Segments)})
+		}
+	}
+	return read
+}
+
+// Calls visit for every note and segment in the order of the input, text is what
+// was written for it and empty for the segments of a booking
+func (read Itinerary) eachParagraph(visit func(text string, line int, segment *Segment)) {
+	notes := read.Notes
+	for i := 0; i <= len(read.Segments); i++ {
+		for len(notes) > 0 && notes[0].After <= i {
+			visit(notes[0].Text, notes[0].Line, nil)
+			notes = notes[1:]
+		}
+		if i < len(read.Segments) {
+			segment := &read.Segments[i]
+			visit(segment.Text, segment.Line, segment)
+		}
+	}
+}
+
+// The airports of the trip in the order of the input, the bare codes of every paragraph
+// and both ends of the segments of a booking, the same one twice in a row only once
+func (read Itinerary) route() []Airport {
+	var route []Airport
+	add := func(airport Airport) {
+		if airport.Name == "" || (len(route) > 0 && route[len(route)-1] == airport) {
+			return
+		}
+		route = append(route, airport)
+	}
+	read.eachParagraph(func(text string, line int, segment *Segment) {
+		if segment != nil && text == "" {
+			add(segment.Origin)
+			add(segment.Destination)
+			return
+		}
+		for _, code := range stopCodes(text) {
+			airport, _ := findAirportByCode(code)
+			add(airport)
+		}
+	})
+	return route
+}
+
+// What DIST(TOTAL) stands for, every leg of the route
+func (read Itinerary) totalDistance() float64 {
+	route := read.route()
+	total := 0.0
+	for i := 1; i < len(route); i++ {
+		total += airportDistance(route[i-1], route[i])
+	}
+	return total
+}
+
+// The airport codes of a text that are stops of the trip, in the order they are written.
+// Codes inside DIST(...), the city and country tokens *#LAX and ^#LAX and codes glued to
+// other text are not
+func stopCodes(text string) []string {
+	text = distanceToken.ReplaceAllStringFunc(text, func(match string) string {
+		return strings.Repeat(" ", len(match))
+	})
+
+	var codes []string
+	for _, loc := range airportToken.FindAllStringIndex(text, -1) {
+		if (loc[0] > 0 && strings.ContainsRune("#*^", rune(text[loc[0]-1]))) || gluedCode(text, loc[0], loc[1]) {
+			continue
+		}
+		codes = append(codes, text[loc[0]:loc[1]])
+	}
+	return codes
+}
+
+// The first airport is the origin and the second the destination, the first time the
+// departure and the next one the arrival, dates count only without any clock time
+func segmentFromParagraph(paragraph string) (Segment, bool) {
+	//The codes inside DIST(...) are not the route and their times no flight
+	text := distanceToken.ReplaceAllStringFunc(paragraph, func(match string) string {
+		return strings.Repeat(" ", len(match))
+	})
+
+	var codes []string
+	for _, code := range stopCodes(text) {
+		//The same airport twice in a row is one stop
+		if len(codes) > 0 && codes[len(codes)-1] == code {
+			continue
+		}
+		codes = append(codes, code)
+	}
+
+	var dates, clocks []time.Time
+	for _, match := range timeToken.FindAllString(text, -1) {
+		stamp, err := parseTimeToken(match)
+		if err != nil {
+			continue
+		}
+		if stamp.kind == "D" {
+			dates = append(dates, stamp.instant)
+		} else {
+			clocks = append(clocks, stamp.instant)
+		}
+	}
+	if len(clocks) == 0 {
+		clocks = dates
+	}
+	if len(codes) < 2 || len(clocks) == 0 {
+		return Segment{}, false
+	}
+
+	segment := Segment{From: codes[0], To: codes[1], Departure: clocks[0]}
+	if len(clocks) > 1 {
+		segment.Arrival = clocks[1]
+		segment.DayShift = calendarDays(segment.Departure, segment.Arrival)
+	}
+	for _, loc := range flightToken.FindAllStringIndex(text, -1) {
+		if (loc[0] > 0 && text[loc[0]-1] == '@') || gluedCode(text, loc[0], loc[1]) {
+			continue
+		}
+		code, number, icao := splitFlight(text[loc[0]:loc[1]])
+		if !airlineCodeShape(code) {
+			continue
+		}
+		segment.Airline, segment.Number = "@"+code, number
+		if icao {
+			segment.Airline = "@@" + code
+		}
+		break
+	}
+	return segment, true
+}
+
+// Looks up the airports of a segment, an unknown code leaves them empty
+func resolveSegment(segment *Segment) {
+	segment.Origin, _ = findAirportByCode(segment.From)
+	segment.Destination, _ = findAirportByCode(segment.To)
+}
+
+// BA283, or empty when the segment names no airline
+func (segment Segment) flight() string {
+	if segment.Airline == "" {
+		return ""
+	}
+	return strings.TrimLeft(segment.Airline, "@") + segment.Number
+}
+
+// The airline name when the lookup knows it
+func (segment Segment) airlineName() (string, bool) {
+	if segment.Airline == "" {
+		return "", false
+	}
+	airline, _, found := findAirlineByToken(segment.Airline + segment.Number)
+	return airline.Name, found
+}
+
+// The same airport, by the lookup when both codes are known
+func sameStop(a Airport, aCode string, b Airport, bCode string) bool {
+	if a.Name != "" && b.Name != "" {
+		return a.ICAO_Code == b.ICAO_Code && a.IATA_Code == b.IATA_Code
+	}
+	return aCode == bCode
+}
+
+// Rules that need the route rather than the markup, a flight that goes nowhere and
+// a flight that leaves from somewhere else than the one before it arrived
+func validateItinerary(path string, read Itinerary) []Diagnostic {
+	var found []Diagnostic
+	problem := func(segment Segment, message string) {
+		d := Diagnostic{Code: diagSegmentRoute, Severity: severityWarning, File: path, Message: message}
+		if segment.Line > 0 {
+			d.Line, d.Column = segment.Line, 1
+		}
+		found = append(found, d)
+	}
+
+	for i, segment := range read.Segments {
+		if sameStop(segment.Origin, segment.From, segment.Destination, segment.To) {
+			problem(segment, fmt.Sprintf("flight from %v to %v starts and ends at the same airport", segment.From, segment.To))
+		}
+		if i == 0 {
+			continue
+		}
+		previous := read.Segments[i-1]
+		if !sameStop(previous.Destination, previous.To, segment.Origin, segment.From) {
+			problem(segment, fmt.Sprintf("flight from %v doesn't leave from %v where the previous flight arrived", segment.From, previous.To))
+		}
+	}
+	return found
+}
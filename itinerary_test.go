@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestItineraryFromMarkup(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	read := itineraryFromMarkup("Dear customer,\r\n\r\n" +
+		"Flight @BA283 from #LAX to *#LAX ##EGLL, DIST(#LHR,#LAX)\n" +
+		"Departs D(2023-06-15T14:00-07:00) T24(2023-06-15T14:00-07:00)\n" +
+		"Arrives T24(2023-06-16T08:30+01:00)\n\n \n" +
+		"Only a date D(2023-06-20T00:00Z) from #LHR\n\n" +
+		"Back from #LHR to #LAX on D(2023-06-25T10:15+01:00)\n")
+
+	if len(read.Segments) != 2 {
+		t.Fatalf("read %d segments, want 2: %+v", len(read.Segments), read.Segments)
+	}
+	first := read.Segments[0]
+	if first.Airline != "@BA" || first.Number != "283" || first.From != "#LAX" || first.To != "##EGLL" || first.Line != 3 {
+		t.Errorf("first segment = %+v", first)
+	}
+	if first.Origin.IATA_Code != "LAX" || first.Destination.ICAO_Code != "EGLL" {
+		t.Errorf("first segment flies %+v to %+v", first.Origin, first.Destination)
+	}
+	if !first.Departure.Equal(time.Date(2023, 6, 15, 21, 0, 0, 0, time.UTC)) || first.DayShift != 1 {
+		t.Errorf("first segment departs %v with shift %d", first.Departure, first.DayShift)
+	}
+	//A date stands in for the departure when there is no time
+	second := read.Segments[1]
+	if second.From != "#LHR" || second.To != "#LAX" || second.Line != 10 || !second.Arrival.IsZero() || second.Airline != "" {
+		t.Errorf("second segment = %+v", second)
+	}
+	want := []Note{{"Dear customer,", 1, 0}, {"Only a date D(2023-06-20T00:00Z) from #LHR", 8, 1}}
+	if !reflect.DeepEqual(read.Notes, want) {
+		t.Errorf("notes = %+v, want %+v", read.Notes, want)
+	}
+	if !strings.HasPrefix(first.Text, "Flight @BA283") || !strings.HasSuffix(first.Text, "T24(2023-06-16T08:30+01:00)") {
+		t.Errorf("first segment was read from %q", first.Text)
+	}
+}
+
+func TestSegmentFromParagraph(t *testing.T) {
+	tests := []struct {
+		paragraph string
+		from, to  string
+		found     bool
+	}{
+		//City and country tokens name a stop, they are not one
+		{"Welcome to *#LHR! Your flight from #LAX to #LHR leaves T24(2023-06-15T14:00-07:00)", "#LAX", "#LHR", true},
+		{"From ^#LAX: #LAX to ##EGLL T24(2023-06-15T14:00-07:00)", "#LAX", "##EGLL", true},
+		{"From #LAX to #LAX and #JFK T24(2023-06-15T14:00-07:00)", "#LAX", "#JFK", true},
+		{"DIST(#LHR,#JFK) from #LAX to #LHR T24(2023-06-15T14:00-07:00)", "#LAX", "#LHR", true},
+		{"Visit *#LHR from #LAX T24(2023-06-15T14:00-07:00)", "", "", false},
+		{"Codes #LAXX and X#JFK from #LAX T24(2023-06-15T14:00-07:00)", "", "", false},
+	}
+	for _, test := range tests {
+		segment, found := segmentFromParagraph(test.paragraph)
+		if found != test.found || segment.From != test.from || segment.To != test.to {
+			t.Errorf("segmentFromParagraph(%q) = %v to %v, %v, want %v to %v, %v", test.paragraph, segment.From, segment.To, found, test.from, test.to, test.found)
+		}
+	}
+}
+
+func TestValidateItinerary(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	read := itineraryFromMarkup("From #LAX to ##KLAX on T24(2023-06-15T14:00-07:00)\n\n" +
+		"From #LAX to #LHR on T24(2023-06-16T14:00-07:00)\n\n" +
+		"From ##EGLL to #LAX on T24(2023-06-20T14:00+01:00)\n\n" +
+		"From #JFK to #LHR on T24(2023-06-21T14:00-04:00)\n")
+
+	var got []string
+	for _, d := range validateItinerary("trip.txt", read) {
+		if d.Code != diagSegmentRoute {
+			t.Errorf("diagnostic code %v, want %v", d.Code, diagSegmentRoute)
+		}
+		got = append(got, d.Message)
+	}
+	//##KLAX is the same airport as #LAX, ##EGLL the same as #LHR
+	want := []string{
+		"flight from #LAX to ##KLAX starts and ends at the same airport",
+		"flight from #JFK doesn't leave from #LAX where the previous flight arrived",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("validateItinerary =\n%v\nwant\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckBookingLines(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	defer func() { diagnostics = nil }()
+	read, err := parseItinerary("trip.pnr", "RP/LONBA0100\n  1.1SMITH/JOHN MR\n  2  BA 283 Y 15JUN 4 LAXZZZ HK1  1400 0830+1\n", bookingNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := checkItinerary("trip.pnr", read)
+	if len(found) != 1 || found[0].Message != "unknown airport code #ZZZ" {
+		t.Fatalf("checkItinerary = %+v, want #ZZZ unknown", found)
+	}
+	//A booking is checked on its segments, so the problem is on the line of the segment
+	if found[0].Line != 3 || found[0].Column != 1 {
+		t.Errorf("#ZZZ reported at %d:%d of the PNR, want 3:1", found[0].Line, found[0].Column)
+	}
+}
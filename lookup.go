@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Correct lookup has 6 columns
+const expectedColumns = 6
+
+var (
+	errLookupNotFound  = errors.New("airport lookup not found")
+	errLookupMalformed = errors.New("airport lookup malformed")
+)
+
+// Reasons a lookup row is skipped
+const (
+	reasonColumns     = "wrong column count"
+	reasonBlank       = "blank field"
+	reasonEncoding    = "invalid text"
+	reasonCodeShape   = "invalid code"
+	reasonCoordinates = "bad coordinates"
+	reasonSwapped     = "swapped coordinates"
+	reasonDuplicate   = "duplicate code"
+)
+
+var (
+	iataShape = regexp.MustCompile(`^[A-Z]{3}$`)
+	icaoShape = regexp.MustCompile(`^[A-Z0-9]{4}$`)
+)
+
+// Position of every column, the lookup may come in any column order
+type lookupColumns struct {
+	name         int
+	country      int
+	municipality int
+	icao         int
+	iata         int
+	coordinates  int
+}
+
+// A row of the lookup as read from the file, with the line it starts on
+type lookupRecord struct {
+	line   int
+	fields []string
+	width  int //Columns of the header when the row doesn't match it
+}
+
+// Why a row could not be used
+type rowProblem struct {
+	reason string
+	detail string
+}
+
+// Turns an error of loadAirports into a diagnostic and the matching exit code
+func reportLookupError(lookupPath string, err error) int {
+	var fileErr *lookupFileError
+	if errors.As(err, &fileErr) {
+		lookupPath = fileErr.path
+	}
+	if errors.Is(err, errLookupMalformed) {
+		report(Diagnostic{Code: diagLookupMalformed, Severity: severityError, File: lookupPath, Message: err.Error()})
+		return exitLookupInvalid
+	}
+	report(Diagnostic{Code: diagLookupMissing, Severity: severityError, File: lookupPath, Message: err.Error()})
+	return exitLookupNotFound
+}
+
+func readLookupRecords(lookupPath string) ([]lookupRecord, error) {
+	//Load the airport-lookup.csv
+	lookup, err := readLookupFile(lookupPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errLookupNotFound, err)
+	}
+
+	//Every format is read into the six known columns
+	format := detectLookupFormat(lookupPath, lookup)
+	records, err := lookupReaders[format].readRecords(lookup)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func parseLookupHeader(header []string) (lookupColumns, bool) {
+	columns := lookupColumns{-1, -1, -1, -1, -1, -1}
+
+	//Adjust for non-standard airport lookup column order
+	for i, head := range header {
+		switch strings.TrimSpace(head) {
+		case "name":
+			columns.name = i
+		case "iso_country":
+			columns.country = i
+		case "municipality":
+			columns.municipality = i
+		case "icao_code":
+			columns.icao = i
+		case "iata_code":
+			columns.iata = i
+		case "coordinates":
+			columns.coordinates = i
+		}
+	}
+
+	//Every column has to be present
+	valid := columns.name >= 0 && columns.country >= 0 && columns.municipality >= 0 &&
+		columns.icao >= 0 && columns.iata >= 0 && columns.coordinates >= 0
+	return columns, valid
+}
+
+func parseLookupRow(row lookupRecord, columns lookupColumns) (Airport, *rowProblem) {
+	record := row.fields
+	if row.width > 0 {
+		return Airport{}, &rowProblem{reasonColumns, fmt.Sprintf("expected %d columns, got %d", row.width, len(record))}
+	}
+	if len(record) != expectedColumns {
+		return Airport{}, &rowProblem{reasonColumns, fmt.Sprintf("expected %d columns, got %d", expectedColumns, len(record))}
+	}
+
+	//Check for empty fields or broken UTF-8 in the lookup
+	header := canonicalHeader
+	order := []int{columns.name, columns.country, columns.municipality, columns.icao, columns.iata, columns.coordinates}
+	for i, column := range order {
+		if strings.TrimSpace(record[column]) == "" {
+			return Airport{}, &rowProblem{reasonBlank, header[i] + " is blank"}
+		}
+	}
+	for i, column := range order {
+		if problem := textProblem(record[column]); problem != "" {
+			return Airport{}, &rowProblem{reasonEncoding, fmt.Sprintf("%v %q %v", header[i], record[column], problem)}
+		}
+	}
+
+	//Codes have to look like codes to ever be matched
+	if !iataShape.MatchString(record[columns.iata]) {
+		return Airport{}, &rowProblem{reasonCodeShape, fmt.Sprintf("IATA code %q is not three capital letters", record[columns.iata])}
+	}
+	if !icaoShape.MatchString(record[columns.icao]) {
+		return Airport{}, &rowProblem{reasonCodeShape, fmt.Sprintf("ICAO code %q is not four capital letters or digits", record[columns.icao])}
+	}
+
+	//Parse the coordinates and skip rows where they don't make sense
+	lat, lon, err := parseCoordinates(record[columns.coordinates], coordinateOrder)
+	if err == errSwappedCoordinates {
+		return Airport{}, &rowProblem{reasonSwapped, fmt.Sprintf("coordinates %q are not in %v order", record[columns.coordinates], coordinateOrder)}
+	} else if err != nil {
+		return Airport{}, &rowProblem{reasonCoordinates, fmt.Sprintf("coordinates %q: %v", record[columns.coordinates], err)}
+	}
+
+	return Airport{
+		Name:         normalizeText(record[columns.name]),
+		ISO_Country:  record[columns.country],
+		Municipality: normalizeText(record[columns.municipality]),
+		ICAO_Code:    record[columns.icao],
+		IATA_Code:    record[columns.iata],
+		Coordinates:  record[columns.coordinates],
+		Latitude:     lat,
+		Longitude:    lon,
+	}, nil
+}
+
+// Ties an error of loadAirports to the lookup file it came from
+type lookupFileError struct {
+	path string
+	err  error
+}
+
+func (e *lookupFileError) Error() string {
+	return e.err.Error()
+}
+
+func (e *lookupFileError) Unwrap() error {
+	return e.err
+}
+
+// Replaces the loaded airports, they are left as they were when a lookup fails
+func loadAirports(lookupPaths ...string) error {
+	//Later files override earlier ones code by code
+	var loaded []Airport
+	for _, lookupPath := range lookupPaths {
+		layer, err := loadLookupFile(lookupPath)
+		if err != nil {
+			return &lookupFileError{lookupPath, err}
+		}
+		if len(loaded) == 0 {
+			loaded = layer
+			continue
+		}
+
+		overridden := map[string]bool{}
+		for _, airport := range layer {
+			overridden["IATA:"+airport.IATA_Code] = true
+			overridden["ICAO:"+airport.ICAO_Code] = true
+		}
+		var merged []Airport
+		replaced := 0
+		for _, airport := range loaded {
+			if overridden["IATA:"+airport.IATA_Code] || overridden["ICAO:"+airport.ICAO_Code] {
+				replaced++
+				continue
+			}
+			merged = append(merged, airport)
+		}
+		loaded = append(merged, layer...)
+
+		if replaced > 0 {
+			report(Diagnostic{Code: diagLookupOverride, Severity: severityInfo, File: lookupPath,
+				Message: fmt.Sprintf("%d airports override earlier lookups", replaced)})
+		}
+	}
+	airports = loaded
+	return nil
+}
+
+func loadLookupFile(lookupPath string) ([]Airport, error) {
+	//An index built by index build skips reading the lookup row by row
+	layer, err := loadIndex(lookupPath)
+	if err == nil {
+		return layer, nil
+	}
+	if errors.Is(err, errIndexStale) {
+		report(Diagnostic{Code: diagIndexStale, Severity: severityInfo, File: indexPath(lookupPath),
+			Message: "index does not match the lookup or its flags, reading the lookup instead, rebuild it with index build"})
+	}
+	return parseLookupFile(lookupPath)
+}
+
+func parseLookupFile(lookupPath string) ([]Airport, error) {
+	records, err := readLookupRecords(lookupPath)
+	if err != nil {
+		return nil, err
+	}
+	columns, valid := parseLookupHeader(records[0].fields)
+	if !valid {
+		return nil, fmt.Errorf("%w: missing columns in the header of %v", errLookupMalformed, lookupPath)
+	}
+
+	//Skip invalid data and count them by reason
+	skipped := map[string]int{}
+	var reasons []string
+	var rows []lookupRow
+	for _, record := range records[1:] {
+		airport, problem := parseLookupRow(record, columns)
+		if problem != nil {
+			if skipped[problem.reason] == 0 {
+				reasons = append(reasons, problem.reason)
+			}
+			skipped[problem.reason]++
+			continue
+		}
+		rows = append(rows, lookupRow{record.line, record.fields, airport})
+	}
+
+	//Rows sharing a code are settled by --duplicates
+	rows, conflicts, err := applyDuplicatePolicy(rows, duplicatePolicy, airportRowKeys)
+	if err != nil {
+		return nil, err
+	}
+	var layer []Airport
+	for _, row := range rows {
+		row.airport.Source = lookupPath
+		layer = append(layer, row.airport)
+	}
+	if len(conflicts) > 0 {
+		report(Diagnostic{Code: diagLookupDuplicate, Severity: severityWarning, File: lookupPath,
+			Message: fmt.Sprintf("%d airport codes are used by more than one row, kept the %v one, see lookup validate", len(conflicts), duplicatePolicy)})
+	}
+
+	//Inform the user of skipped records
+	for _, reason := range reasons {
+		message := fmt.Sprintf("could not read %d airport records, %v", skipped[reason], reason)
+		if reason == reasonSwapped {
+			message += ", try --coords"
+		}
+		report(Diagnostic{Code: diagLookupRow, Severity: severityWarning, File: lookupPath, Message: message})
+	}
+	return layer, nil
+}
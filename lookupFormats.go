@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Set with --lookup-format and --columns
+var lookupFormat = "auto"
+var columnMapping string
+
+// The columns every reader turns its rows into, in this order
+var canonicalHeader = []string{"name", "iso_country", "municipality", "icao_code", "iata_code", "coordinates"}
+
+// Turns the content of a lookup file into rows of the canonical columns, header first
+type lookupReader interface {
+	readRecords(content string) ([]lookupRecord, error)
+}
+
+var lookupReaders = map[string]lookupReader{
+	"csv":         delimitedReader{comma: ','},
+	"tsv":         delimitedReader{comma: '\t'},
+	"json":        jsonReader{},
+	"ourairports": ourAirportsReader{},
+}
+
+// Registers the flags of the lookup loader shared by every command
+func addLookupFlags(flags *flag.FlagSet) {
+	flags.Var(&lookupFlags, "lookup", "Airport lookup to use instead of the embedded one, may be repeated")
+	flags.StringVar(&coordinateOrder, "coords", "lonlat", "Order of the lookup coordinates: lonlat or latlon")
+	flags.StringVar(&duplicatePolicy, "duplicates", "first", "Which lookup row keeps a shared code: first, last or error")
+	flags.StringVar(&lookupFormat, "lookup-format", "auto", "Lookup format: auto, csv, tsv, json or ourairports")
+	flags.StringVar(&columnMapping, "columns", "", "Header names of a custom CSV, e.g. name=Airport,iata_code=IATA")
+}
+
+func checkLookupFlags() error {
+	if !coordinateOrders[coordinateOrder] {
+		return fmt.Errorf("unknown coordinate order %v", coordinateOrder)
+	}
+	if !duplicatePolicies[duplicatePolicy] {
+		return fmt.Errorf("unknown duplicate policy %v", duplicatePolicy)
+	}
+	if _, exists := lookupReaders[lookupFormat]; !exists && lookupFormat != "auto" {
+		return fmt.Errorf("unknown lookup format %v", lookupFormat)
+	}
+	if _, err := parseColumnMapping(columnMapping); err != nil {
+		return err
+	}
+	return nil
+}
+
+func parseColumnMapping(mapping string) (map[string]string, error) {
+	//canonical=header pairs separated by commas
+	columns := map[string]string{}
+	if strings.TrimSpace(mapping) == "" {
+		return columns, nil
+	}
+	for _, pair := range strings.Split(mapping, ",") {
+		canonical, header, found := strings.Cut(pair, "=")
+		canonical = strings.TrimSpace(canonical)
+		if !found || !isCanonicalColumn(canonical) {
+			return nil, fmt.Errorf("invalid column mapping %q, expected one of %v=<header>", pair, strings.Join(canonicalHeader, "|"))
+		}
+		columns[canonical] = strings.TrimSpace(header)
+	}
+	return columns, nil
+}
+
+func isCanonicalColumn(name string) bool {
+	for _, column := range canonicalHeader {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+func detectLookupFormat(path string, content string) string {
+	if lookupFormat != "auto" {
+		return lookupFormat
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsv":
+		return "tsv"
+	case ".json":
+		return "json"
+	}
+
+	//OurAirports has separate latitude and longitude columns
+	header, _, _ := strings.Cut(content, "\n")
+	if strings.Contains(header, "latitude_deg") && strings.Contains(header, "ident") {
+		return "ourairports"
+	}
+	return "csv"
+}
+
+func joinCoordinates(lat, lon string) string {
+	//Keep the configured convention so parseCoordinates reads them back the same way
+	if coordinateOrder == "latlon" {
+		return lat + ", " + lon
+	}
+	return lon + ", " + lat
+}
+
+func lineAt(content string, offset int64) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
+
+// CSV or TSV, in the six column layout or any layout described by --columns
+type delimitedReader struct {
+	comma rune
+}
+
+func (d delimitedReader) readRecords(content string) ([]lookupRecord, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.Comma = d.comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = d.comma == '\t'
+
+	var rows []lookupRecord
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: error reading CSV: %v", errLookupMalformed, err)
+		}
+		line, _ := r.FieldPos(0)
+		rows = append(rows, lookupRecord{line: line, fields: fields})
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: the lookup is empty", errLookupMalformed)
+	}
+
+	//Without a mapping the header must be the six known columns in any order
+	mapping, _ := parseColumnMapping(columnMapping)
+	if len(mapping) == 0 && len(rows[0].fields) != expectedColumns {
+		return nil, fmt.Errorf("%w: invalid amount of columns, map them with --columns", errLookupMalformed)
+	}
+
+	//Find every canonical column by its mapped header, or else by its own name, so a
+	//mapped lookup can be layered with lookups in the standard layout
+	headers := map[string]int{}
+	for i, head := range rows[0].fields {
+		headers[strings.TrimSpace(head)] = i
+	}
+	position := map[string]int{}
+	for _, canonical := range canonicalHeader {
+		if i, found := headers[mapping[canonical]]; found && mapping[canonical] != "" {
+			position[canonical] = i
+		} else if i, found := headers[canonical]; found {
+			position[canonical] = i
+		}
+	}
+	if len(position) != len(canonicalHeader) {
+		var missing []string
+		for _, canonical := range canonicalHeader {
+			if _, found := position[canonical]; !found {
+				missing = append(missing, canonical)
+			}
+		}
+		return nil, fmt.Errorf("%w: missing columns %v in the header", errLookupMalformed, strings.Join(missing, ", "))
+	}
+
+	records := []lookupRecord{{line: rows[0].line, fields: canonicalHeader}}
+	for _, row := range rows[1:] {
+		//Rows of the wrong length are passed on as they are and rejected by the row check
+		if len(row.fields) != len(rows[0].fields) {
+			records = append(records, lookupRecord{line: row.line, fields: row.fields, width: len(rows[0].fields)})
+			continue
+		}
+		fields := make([]string, len(canonicalHeader))
+		for i, canonical := range canonicalHeader {
+			fields[i] = row.fields[position[canonical]]
+		}
+		records = append(records, lookupRecord{line: row.line, fields: fields})
+	}
+	return records, nil
+}
+
+// The airports.csv export of OurAirports
+type ourAirportsReader struct{}
+
+func (ourAirportsReader) readRecords(content string) ([]lookupRecord, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.FieldsPerRecord = -1
+
+	var records []lookupRecord
+	position := map[string]int{}
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: error reading CSV: %v", errLookupMalformed, err)
+		}
+		line, _ := r.FieldPos(0)
+
+		if len(records) == 0 {
+			for i, head := range fields {
+				position[strings.TrimSpace(head)] = i
+			}
+			for _, required := range []string{"ident", "name", "iso_country", "municipality", "iata_code", "gps_code", "latitude_deg", "longitude_deg"} {
+				if _, found := position[required]; !found {
+					return nil, fmt.Errorf("%w: missing column %v in the OurAirports header", errLookupMalformed, required)
+				}
+			}
+			records = append(records, lookupRecord{line: line, fields: canonicalHeader})
+			continue
+		}
+
+		field := func(name string) string {
+			if i, found := position[name]; found && i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+
+		//Newer exports have a separate icao_code, older ones only the GPS code or the ident
+		icao := field("icao_code")
+		if icao == "" {
+			icao = field("gps_code")
+		}
+		if icao == "" {
+			icao = field("ident")
+		}
+		lat, lon := field("latitude_deg"), field("longitude_deg")
+		coordinates := ""
+		if lat != "" && lon != "" {
+			coordinates = joinCoordinates(lat, lon)
+		}
+		records = append(records, lookupRecord{line: line, fields: []string{
+			field("name"), field("iso_country"), field("municipality"), icao, field("iata_code"), coordinates,
+		}})
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: the lookup is empty", errLookupMalformed)
+	}
+	return records, nil
+}
+
+// A JSON array of airport objects
+type jsonReader struct{}
+
+type jsonAirport struct {
+	Name         string   `json:"name"`
+	ISO_Country  string   `json:"iso_country"`
+	Municipality string   `json:"municipality"`
+	ICAO_Code    string   `json:"icao_code"`
+	IATA_Code    string   `json:"iata_code"`
+	Coordinates  string   `json:"coordinates"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+}
+
+func (jsonReader) readRecords(content string) ([]lookupRecord, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, fmt.Errorf("%w: expected a JSON array of airports", errLookupMalformed)
+	}
+
+	records := []lookupRecord{{line: 1, fields: canonicalHeader}}
+	for decoder.More() {
+		//Skip the whitespace so the line is the one the object starts on
+		offset := decoder.InputOffset()
+		for offset < int64(len(content)) && strings.ContainsRune(" \t\r\n,", rune(content[offset])) {
+			offset++
+		}
+
+		var airport jsonAirport
+		if err := decoder.Decode(&airport); err != nil {
+			return nil, fmt.Errorf("%w: error reading JSON on line %d: %v", errLookupMalformed, lineAt(content, offset), err)
+		}
+
+		coordinates := airport.Coordinates
+		if coordinates == "" && airport.Latitude != nil && airport.Longitude != nil {
+			coordinates = joinCoordinates(strconv.FormatFloat(*airport.Latitude, 'f', -1, 64), strconv.FormatFloat(*airport.Longitude, 'f', -1, 64))
+		}
+		records = append(records, lookupRecord{line: lineAt(content, offset), fields: []string{
+			airport.Name, airport.ISO_Country, airport.Municipality, airport.ICAO_Code, airport.IATA_Code, coordinates,
+		}})
+	}
+	return records, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// The records a reader gives for Heathrow, the same whatever the format
+var heathrowRecords = [][]string{
+	canonicalHeader,
+	{"London Heathrow Airport", "GB", "London", "EGLL", "LHR", "-0.461941, 51.4706"},
+}
+
+func recordFields(records []lookupRecord) [][]string {
+	var fields [][]string
+	for _, record := range records {
+		fields = append(fields, record.fields)
+	}
+	return fields
+}
+
+func TestLookupReaders(t *testing.T) {
+	defer func() { columnMapping = "" }()
+	tests := []struct {
+		name    string
+		format  string
+		columns string
+		content string
+	}{
+		{"csv", "csv", "", "municipality,name,iata_code,icao_code,iso_country,coordinates\n" +
+			"London,London Heathrow Airport,LHR,EGLL,GB,\"-0.461941, 51.4706\"\n"},
+		{"tsv", "tsv", "", "name\tiso_country\tmunicipality\ticao_code\tiata_code\tcoordinates\n" +
+			"London Heathrow Airport\tGB\tLondon\tEGLL\tLHR\t-0.461941, 51.4706\n"},
+		{"json with coordinates", "json", "", `[{"name": "London Heathrow Airport", "iso_country": "GB", "municipality": "London",
+			"icao_code": "EGLL", "iata_code": "LHR", "coordinates": "-0.461941, 51.4706"}]`},
+		{"json with numbers", "json", "", `[{"name": "London Heathrow Airport", "iso_country": "GB", "municipality": "London",
+			"icao_code": "EGLL", "iata_code": "LHR", "latitude": 51.4706, "longitude": -0.461941}]`},
+		{"ourairports", "ourairports", "", "id,ident,type,name,latitude_deg,longitude_deg,iso_country,municipality,gps_code,iata_code\n" +
+			"2434,EGLL,large_airport,London Heathrow Airport,51.4706,-0.461941,GB,London,EGLL,LHR\n"},
+		{"ourairports without a gps code", "ourairports", "", "ident,name,latitude_deg,longitude_deg,iso_country,municipality,gps_code,iata_code\n" +
+			"EGLL,London Heathrow Airport,51.4706,-0.461941,GB,London,,LHR\n"},
+		{"csv with --columns", "csv", "name=Airport,iso_country=Country,municipality=City,icao_code=ICAO,iata_code=IATA,coordinates=Location",
+			"IATA,Airport,City,Country,ICAO,Location,Elevation\nLHR,London Heathrow Airport,London,GB,EGLL,\"-0.461941, 51.4706\",83\n"},
+		//A file that already has a canonical column doesn't need it mapped
+		{"csv with some --columns", "csv", "name=Airport,iata_code=IATA",
+			"IATA,Airport,municipality,iso_country,icao_code,coordinates\nLHR,London Heathrow Airport,London,GB,EGLL,\"-0.461941, 51.4706\"\n"},
+	}
+	for _, test := range tests {
+		columnMapping = test.columns
+		records, err := lookupReaders[test.format].readRecords(test.content)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if got := recordFields(records); !reflect.DeepEqual(got, heathrowRecords) {
+			t.Errorf("%v: read %q, want %q", test.name, got, heathrowRecords)
+		}
+	}
+}
+
+func TestLookupReaderErrors(t *testing.T) {
+	defer func() { columnMapping = "" }()
+	tests := []struct {
+		name    string
+		format  string
+		columns string
+		content string
+	}{
+		{"csv with a column too many", "csv", "", "name,iso_country,municipality,icao_code,iata_code,coordinates,elevation\n"},
+		{"csv with a mapped header missing", "csv", "name=Airport", "Name,iso_country,municipality,icao_code,iata_code,coordinates\n"},
+		{"empty tsv", "tsv", "", ""},
+		{"json object", "json", "", `{"name": "London Heathrow Airport"}`},
+		{"json with a broken object", "json", "", `[{"name": "London Heathrow Airport",}]`},
+		{"ourairports without coordinates", "ourairports", "", "ident,name,iso_country,municipality,gps_code,iata_code\n"},
+	}
+	for _, test := range tests {
+		columnMapping = test.columns
+		if _, err := lookupReaders[test.format].readRecords(test.content); err == nil {
+			t.Errorf("%v gave no error", test.name)
+		}
+	}
+}
+
+func TestDetectLookupFormat(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    string
+	}{
+		{"airports.csv", "name,iso_country,municipality,icao_code,iata_code,coordinates\n", "csv"},
+		{"airports.TSV", "", "tsv"},
+		{"airports.json", "[]", "json"},
+		{"airports.csv", "id,ident,type,name,latitude_deg,longitude_deg\n", "ourairports"},
+		{embeddedLookupPath, embeddedLookup, "csv"},
+	}
+	for _, test := range tests {
+		if got := detectLookupFormat(test.path, test.content); got != test.want {
+			t.Errorf("detectLookupFormat(%v) = %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestLayeredColumns(t *testing.T) {
+	defer func() { columnMapping, airports, diagnostics = "", nil, nil }()
+	dir := t.TempDir()
+	standard := filepath.Join(dir, "l.tsv")
+	mapped := filepath.Join(dir, "cm.csv")
+	if err := os.WriteFile(standard, []byte("name\tiso_country\tmunicipality\ticao_code\tiata_code\tcoordinates\n"+
+		"London Heathrow Airport\tGB\tLondon\tEGLL\tLHR\t-0.461941, 51.4706\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mapped, []byte("Airport,Country,City,ICAO,IATA,Location\n"+
+		"Los Angeles International Airport,US,Los Angeles,KLAX,LAX,\"-118.408, 33.9425\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	//--columns describes cm.csv, l.tsv is read by the standard names
+	columnMapping = "name=Airport,iso_country=Country,municipality=City,icao_code=ICAO,iata_code=IATA,coordinates=Location"
+	if err := loadAirports(standard, mapped); err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	for _, airport := range airports {
+		codes = append(codes, airport.IATA_Code)
+	}
+	if !reflect.DeepEqual(codes, []string{"LHR", "LAX"}) {
+		t.Errorf("layered lookups gave %v, want LHR and LAX", codes)
+	}
+}
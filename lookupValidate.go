@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Outcome of validating every row of a lookup
+type lookupSummary struct {
+	rows    int
+	valid   int
+	skipped map[string]int
+	kept    [][]string
+}
+
+func validateLookupRecords(lookupPath string, records []lookupRecord, columns lookupColumns) (lookupSummary, error) {
+	summary := lookupSummary{skipped: map[string]int{}, kept: [][]string{records[0].fields}}
+
+	var rows []lookupRow
+	for _, record := range records[1:] {
+		summary.rows++
+		airport, problem := parseLookupRow(record, columns)
+		if problem != nil {
+			summary.skipped[problem.reason]++
+			reportAt(severityWarning, diagLookupRow, lookupPath, record.line, 1, "%v: %v", problem.reason, problem.detail)
+			continue
+		}
+		rows = append(rows, lookupRow{record.line, record.fields, airport})
+	}
+
+	//Conflicts are reported on the row that caused them
+	rows, conflicts, err := applyDuplicatePolicy(rows, duplicatePolicy, airportRowKeys)
+	for _, conflict := range conflicts {
+		sev := severityInfo
+		if conflict.conflicting {
+			sev = severityWarning
+		}
+		reportAt(sev, diagLookupDuplicate, lookupPath, conflict.line, 1, "%v: %v", reasonDuplicate, conflict)
+	}
+	if err != nil {
+		return summary, err
+	}
+
+	for _, row := range rows {
+		summary.kept = append(summary.kept, row.fields)
+	}
+	summary.valid = len(rows)
+	if dropped := summary.rows - summary.valid - countSkipped(summary); dropped > 0 {
+		summary.skipped[reasonDuplicate] = dropped
+	}
+	return summary, nil
+}
+
+func countSkipped(summary lookupSummary) int {
+	total := 0
+	for _, count := range summary.skipped {
+		total += count
+	}
+	return total
+}
+
+func writeCleanLookup(path string, rows [][]string) error {
+	var cleaned bytes.Buffer
+	w := csv.NewWriter(&cleaned)
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, cleaned.Bytes())
+}
+
+// Prints the counts of a validated lookup, the path coloured only on a terminal
+func printLookupSummary(w io.Writer, lookupPath string, summary lookupSummary, color bool) {
+	if color {
+		fmt.Fprintf(w, "\n%s%v%s\n", Blue, lookupPath, Reset)
+	} else {
+		fmt.Fprintf(w, "\n%v\n", lookupPath)
+	}
+	fmt.Fprintf(w, "  %d rows, %d valid, %d skipped\n", summary.rows, summary.valid, summary.rows-summary.valid)
+
+	//Most common reasons first
+	var reasons []string
+	for reason := range summary.skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if summary.skipped[reasons[i]] != summary.skipped[reasons[j]] {
+			return summary.skipped[reasons[i]] > summary.skipped[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	for _, reason := range reasons {
+		fmt.Fprintf(w, "  %6d %v\n", summary.skipped[reason], reason)
+	}
+}
+
+func registerValidateFlags(flags *flag.FlagSet) *string {
+	clean := flags.String("clean", "", "Write the valid rows into this CSV file")
+	addLookupFlags(flags)
+	flags.StringVar(&diagnosticsFormat, "diagnostics", "text", "Format of the row reports: text or json")
+	return clean
+}
+
+func runLookupValidate(args []string) int {
+	validateFlags := newFlagSet("lookup validate")
+	clean := registerValidateFlags(validateFlags)
+	if err := parseFlags(validateFlags, args); err != nil {
+		return usageExit(err)
+	}
+	if len(validateFlags.Args()) > 1 {
+		validateFlags.Usage()
+		return exitUsage
+	}
+
+	defer flushDiagnostics()
+
+	if err := checkDiagnosticsFormat(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return exitUsage
+	}
+	if err := checkLookupFlags(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return exitUsage
+	}
+
+	lookupPath := chooseLookups(validateFlags.Args())[0]
+	records, err := readLookupRecords(lookupPath)
+	if err != nil {
+		return reportLookupError(lookupPath, err)
+	}
+	columns, valid := parseLookupHeader(records[0].fields)
+	if !valid {
+		return reportLookupError(lookupPath, fmt.Errorf("%w: missing columns in the header of %v", errLookupMalformed, lookupPath))
+	}
+
+	//Row reports first, the summary closes the output
+	summary, err := validateLookupRecords(lookupPath, records, columns)
+	if err != nil {
+		return reportLookupError(lookupPath, err)
+	}
+	if diagnosticsFormat == "text" {
+		flushDiagnostics()
+		printLookupSummary(os.Stdout, lookupPath, summary, isTerminal(os.Stdout))
+	}
+
+	if *clean != "" {
+		if err := writeCleanLookup(*clean, summary.kept); err != nil {
+			report(Diagnostic{Code: diagOutputFailed, Severity: severityError, File: *clean, Message: "error writing cleaned lookup: " + err.Error()})
+			return exitOutput
+		}
+		if diagnosticsFormat == "text" {
+			fmt.Printf("  %d rows written to %v\n", summary.valid, *clean)
+		}
+	}
+
+	if summary.valid < summary.rows {
+		return exitProblems
+	}
+	return exitOK
+}
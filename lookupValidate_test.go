@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validateLookup = "name,iso_country,municipality,icao_code,iata_code,coordinates\n" +
+	"London Heathrow Airport,GB,London,EGLL,LHR,\"-0.461941, 51.4706\"\n" +
+	"Los Angeles International Airport,US,Los Angeles,KLAX,LAX,\"-118.408, 33.9425\"\n" +
+	",GB,London,EGLC,LCY,\"0.055, 51.505\"\n" +
+	"Gatwick,GB,London,EGKK,lgw,\"-0.190, 51.148\"\n" +
+	"Heathrow,GB,London,EGLL,LHR,\"-0.461941, 51.4706\"\n" +
+	"Sydney Kingsford Smith International Airport,AU,Sydney,YSSY,SYD,\"-33.946, 151.177\"\n" +
+	"Luton,GB,London,EGGW,LTN\n"
+
+func writeValidateLookup(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "airports.csv")
+	if err := os.WriteFile(path, []byte(validateLookup), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateLookupRecords(t *testing.T) {
+	defer func() { diagnostics = nil }()
+	diagnostics = nil
+	path := writeValidateLookup(t)
+	records, err := readLookupRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns, valid := parseLookupHeader(records[0].fields)
+	if !valid {
+		t.Fatal("the header is not valid")
+	}
+
+	summary, err := validateLookupRecords(path, records, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.rows != 7 || summary.valid != 2 {
+		t.Errorf("%d rows, %d valid, want 7 rows, 2 valid", summary.rows, summary.valid)
+	}
+	wantSkipped := map[string]int{reasonBlank: 1, reasonCodeShape: 1, reasonDuplicate: 1, reasonSwapped: 1, reasonColumns: 1}
+	if !reflect.DeepEqual(summary.skipped, wantSkipped) {
+		t.Errorf("skipped = %v, want %v", summary.skipped, wantSkipped)
+	}
+	//The header and the valid rows are kept for --clean
+	if len(summary.kept) != 3 || summary.kept[1][4] != "LHR" || summary.kept[2][4] != "LAX" {
+		t.Errorf("kept %v, want the header, LHR and LAX", summary.kept)
+	}
+
+	//Every skipped row is reported on its line
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, strings.SplitN(d.String(), ":", 3)[1]+" "+d.Code)
+	}
+	want := []string{"4 lookup-row-skipped", "5 lookup-row-skipped", "7 lookup-row-skipped", "8 lookup-row-skipped", "6 lookup-duplicate", "6 lookup-duplicate"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reported %v, want %v", got, want)
+	}
+
+	//--duplicates error stops at the first conflict
+	duplicatePolicy = "error"
+	defer func() { duplicatePolicy = "first" }()
+	if _, err := validateLookupRecords(path, records, columns); err == nil {
+		t.Error("a conflict with --duplicates error gave no error")
+	}
+}
+
+func TestRunLookupValidate(t *testing.T) {
+	path := writeValidateLookup(t)
+	cleaned := filepath.Join(t.TempDir(), "clean.csv")
+	defer func() { lookupFlags, diagnosticsFormat = nil, "text" }()
+
+	exit, output := runCaptured(t, []string{"lookup", "validate", "--clean", cleaned, path})
+	if exit != exitProblems {
+		t.Errorf("validating a lookup with bad rows exited with %d, want %d", exit, exitProblems)
+	}
+	//Piped, so without colours
+	want := "\n" + path + "\n  7 rows, 2 valid, 5 skipped\n" +
+		"       1 blank field\n       1 duplicate code\n       1 invalid code\n       1 swapped coordinates\n       1 wrong column count\n" +
+		"  2 rows written to " + cleaned + "\n"
+	if output != want {
+		t.Errorf("summary =\n%q\nwant\n%q", output, want)
+	}
+	content, err := os.ReadFile(cleaned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); len(lines) != 3 {
+		t.Errorf("cleaned lookup has %d lines, want the header and 2 rows:\n%s", len(lines), content)
+	}
+
+	//The cleaned lookup is valid
+	if exit, _ := runCaptured(t, []string{"lookup", "validate", cleaned}); exit != exitOK {
+		t.Errorf("validating the cleaned lookup exited with %d, want %d", exit, exitOK)
+	}
+	if exit, _ := runCaptured(t, []string{"lookup", "validate", filepath.Join("testdata", "lookups", "missing-column.csv")}); exit != exitLookupInvalid {
+		t.Errorf("validating a lookup without an iata_code column exited with %d, want %d", exit, exitLookupInvalid)
+	}
+	if exit, _ := runCaptured(t, []string{"lookup", "validate", filepath.Join(t.TempDir(), "missing.csv")}); exit != exitLookupNotFound {
+		t.Errorf("validating a missing lookup exited with %d, want %d", exit, exitLookupNotFound)
+	}
+}
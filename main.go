@@ -1,13 +1,11 @@
 package main
 
 import (
-	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"os"
-	"strconv"
-	"strings"
+	"time"
 )
 
 type Airport struct {
@@ -17,11 +15,13 @@ type Airport struct {
 	ICAO_Code    string
 	IATA_Code    string
 	Coordinates  string
+	Latitude     float64
+	Longitude    float64
+	Source       string //Lookup file the airport was read from
 }
 
 // Declare a slice of airport structs and variables for bonuses
 var airports []Airport
-var outputType string
 
 const ( //Terminal color constants
 	Red    = "\033[31m"
@@ -31,22 +31,6 @@ const ( //Terminal color constants
 	Reset  = "\033[0m"
 )
 
-// Define a map for month number to name mapping
-var monthMap = map[string]string{
-	"01": "Jan",
-	"02": "Feb",
-	"03": "Mar",
-	"04": "Apr",
-	"05": "May",
-	"06": "Jun",
-	"07": "Jul",
-	"08": "Aug",
-	"09": "Sep",
-	"10": "Oct",
-	"11": "Nov",
-	"12": "Dec",
-}
-
 var options = map[int]string{
 	1: "Overwrite",
 	2: "Change Name",
@@ -69,300 +53,210 @@ func loadFile(path string) (string, error) {
 	return string(content), nil
 }
 
-func validString(input string) bool {
-	for _, r := range input {
-		if rune(r) > 127 {
-			return false
-		}
-	}
-	return true
+func main() {
+	//Subcommands are picked before the flags of the conversion
+	os.Exit(runCommand(os.Args[1:]))
 }
 
-func printHelp() {
-	//Help is printed when there are no arguments or -h flag is used
-	fmt.Printf("\n%sItinerary usage:%s\n", Blue, Reset)
-	fmt.Printf("  go run . -h %s-- Show this help message%s\n", Red, Reset)
-	fmt.Printf("  go run . %s[-o]/[-r]%s ./input.txt ./output.txt ./airport-lookup.csv %s-- Proper use of program%s\n", Yellow, Reset, Green, Reset)
-	fmt.Printf("\n%sDescription:%s\n", Yellow, Reset)
-	fmt.Printf("  -o %s- Automatically overwrites your output without prompting%s\n", Yellow, Reset)
-	fmt.Printf("  -r %s- Automatically rewrites your output name without prompting%s\n", Yellow, Reset)
-	fmt.Println("  This program prettifies your itinerary that you input.")
-	fmt.Println("  Providing invalid input will result in an error message")
-	println("")
+// Flags of the conversion that are not kept in globals
+type convertOptions struct {
+	overwrite   *bool
+	rewrite     *bool
+	units       *string
+	onCollision *string
+	strict      *bool
 }
 
-func main() {
-	//Define the help flag
-	showHelp := flag.Bool("h", false, "Show help information")
-	overwrite := flag.Bool("o", false, "Enable overwrite mode")
-	rewrite := flag.Bool("r", false, "Enable rewrite mode")
-	flag.Parse()
-
-	//Show help if no arguments are found or -h flag is used
-	if *showHelp || len(flag.Args()) == 0 {
-		printHelp()
-		return
-	}
+func registerConvertFlags(flags *flag.FlagSet) convertOptions {
+	var opts convertOptions
+	opts.overwrite = flags.Bool("o", false, "Enable overwrite mode, same as --on-collision overwrite")
+	flags.BoolVar(opts.overwrite, "overwrite", false, "Long name of -o")
+	opts.rewrite = flags.Bool("r", false, "Enable rewrite mode, same as --on-collision rename")
+	flags.BoolVar(opts.rewrite, "rename", false, "Long name of -r")
+	opts.units = flags.String("units", "km", "Distance units: km, mi or nm")
+	flags.StringVar(&offsetStyle, "offset-style", "iso", "How offsets are written: iso (+05:30), utc (UTC+5:30) or gmt (GMT+5:30)")
+	flags.StringVar(&backupMode, "backup", "", "Keep the replaced output as name.bak (bak) or in .history/ (history)")
+	opts.onCollision = flags.String("on-collision", "prompt", "When the output exists: prompt, overwrite, rename, timestamp or fail")
+	opts.strict = flags.Bool("strict", false, "Fail when the input has unresolved airport codes")
+	flags.BoolVar(&asciiOutput, "ascii", false, "Transliterate the output to ASCII")
+	addLookupFlags(flags)
+	addAirlineFlags(flags)
+	addInputFlags(flags)
+	flags.StringVar(&diagnosticsFormat, "diagnostics", "text", "Format of warnings and errors: text or json")
+	return opts
+}
 
-	if len(flag.Args()) < 3 {
-		fmt.Println("Error: Not enough arguments provided.")
-		printHelp()
-		return
+// Checks the options every conversion shares and picks the collision policy
+func checkConvertOptions(opts convertOptions) (CollisionPolicy, int) {
+	//Check for a known diagnostics format
+	if err := checkDiagnosticsFormat(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return nil, exitUsage
 	}
 
-	//Store arguments for convenience
-	inputPath := flag.Args()[0]
-	outputPath := flag.Args()[1]
-	lookupPath := flag.Args()[2]
+	//Check for a known distance unit
+	if _, exists := distanceUnits[*opts.units]; !exists {
+		reportf(severityError, diagUsage, "unknown distance unit %v", *opts.units)
+		return nil, exitUsage
+	}
+	distanceUnit = *opts.units
 
-	//Load the input and check if it exists
-	userInput, err := loadFile(inputPath)
-	if err != nil {
-		log.Println("Error:", err)
-		fmt.Println("Input not found")
-		return
+	//Check for a known input format
+	if err := checkInputFormat(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return nil, exitUsage
 	}
 
-	//Check for type of output
-	outputType = "txt"
-	if strings.HasSuffix(outputPath, ".html") {
-		outputType = "html"
+	//Check for a known offset style
+	if !offsetStyles[offsetStyle] {
+		reportf(severityError, diagUsage, "unknown offset style %v, expected iso, utc or gmt", offsetStyle)
+		return nil, exitUsage
 	}
 
-	//Load the airport-lookup.csv
-	lookup, err := loadFile(lookupPath)
-	if err != nil {
-		log.Println("Error", err)
-		fmt.Println("Airport lookup not found")
-		return
+	//Check the coordinate order, duplicate policy and lookup format
+	if err := checkLookupFlags(); err != nil {
+		reportf(severityError, diagUsage, "%v", err)
+		return nil, exitUsage
 	}
 
-	//Reading the airport-lookup.csv
-	r := csv.NewReader(strings.NewReader(lookup))
-	records, err := r.ReadAll()
+	//Check for a known backup mode
+	if !backupModes[backupMode] {
+		reportf(severityError, diagUsage, "unknown backup mode %v, expected bak or history", backupMode)
+		return nil, exitUsage
+	}
 
+	//-o and -r are short for the two most common policies
+	policyName := *opts.onCollision
+	if *opts.overwrite {
+		policyName = "overwrite"
+	} else if *opts.rewrite {
+		policyName = "rename"
+	}
+	policy, err := newCollisionPolicy(policyName)
 	if err != nil {
-		fmt.Printf("\n%sError reading CSV: %v%s\nAirport lookup malformed\n", Red, err, Reset)
-
-		return //Interrupt if there's something wrong with the file
+		reportf(severityError, diagUsage, "%v", err)
+		return nil, exitUsage
 	}
+	return policy, exitOK
+}
 
-	//Correct lookup has 6 columns
-	const expectedColumns = 6
-
-	//Check for correct amount of columns in the header
-	if len(records[0]) != expectedColumns {
-		fmt.Printf("\n%sInvalid amount of columns - data malformed in %v %s\n", Yellow, lookupPath, Reset)
-		os.Exit(1) //Exit with an error
+func runConvert(args []string) int {
+	//Flags, then the environment, then the config files
+	convertFlags := newFlagSet("convert")
+	opts := registerConvertFlags(convertFlags)
+	if err := parseFlags(convertFlags, args); err != nil {
+		return usageExit(err)
 	}
 
-	var invalidLookupRows int
-	var nameid *int
-	var countryid *int
-	var municid *int
-	var icaoid *int
-	var iataid *int
-	var coordid *int
-
-	for i, record := range records {
-		//Adjust for non-standard airport lookup column order
-		if i == 0 {
-			for i, head := range record {
-				switch head {
-				case "name":
-					nameid = &i
-				case "iso_country":
-					countryid = &i
-				case "municipality":
-					municid = &i
-				case "icao_code":
-					icaoid = &i
-				case "iata_code":
-					iataid = &i
-				case "coordinates":
-					coordid = &i
-				}
-			}
-
-			continue
-		}
-
-		if len(record) != expectedColumns {
-			fmt.Printf("\n%sSkipping row %d: Expected %d columns, got %d%s\n", Yellow, i+1, expectedColumns, len(record), Reset)
-			continue
-		}
-
-		//Check for empty fields or UTF-8 exceeding characters in the lookup
-		malformedRow := false
-		for i, data := range record {
-			if strings.TrimSpace(data) == "" {
-				malformedRow = true
-				break
-			}
-			if i != 5 {
-				if !validString(data) {
-					malformedRow = true
-				}
-			}
-		}
-
-		//Skip invalid data and count them
-		if malformedRow {
-			invalidLookupRows++
-			continue
-		}
-
-		// Append valid data
-		airport := Airport{
-			Name:         record[*nameid],
-			ISO_Country:  record[*countryid],
-			Municipality: record[*municid],
-			ICAO_Code:    record[*icaoid],
-			IATA_Code:    record[*iataid],
-			Coordinates:  record[*coordid],
-		}
-
-		airports = append(airports, airport)
+	//Diagnostics are printed whenever the run ends
+	defer flushDiagnostics()
 
+	//Show help if no arguments are found
+	if len(convertFlags.Args()) == 0 {
+		printHelp()
+		return exitOK
 	}
 
-	//Inform the user of skipped records
-	if invalidLookupRows > 0 {
-		fmt.Printf("\n%sCould not read %d airport records. Exceeded UTF-8 characters%s\n", Yellow, invalidLookupRows, Reset)
+	if len(convertFlags.Args()) < 2 {
+		reportf(severityError, diagUsage, "not enough arguments provided")
+		convertFlags.Usage()
+		return exitUsage
 	}
 
-	//Check if the output already exists
-	exist := false
-	if _, err := os.Stat(outputPath); err == nil {
-		exist = true
-	} else if os.IsNotExist(err) {
-		exist = false
-	} else {
-		//Some other error occurred (e.g., permission issues)
-		fmt.Printf("Could not access file %v, option to overwrite is unavailable - %v\n", outputPath, err)
+	policy, exit := checkConvertOptions(opts)
+	if exit != exitOK {
+		return exit
 	}
 
-	//Prompt the user to choose to Overwrite / Keep / Cancel
-	var choice int
-	var newOutputPath string
-	originalOutputPath := outputPath
-	iterate := 1
-	if exist && !*overwrite {
-		for {
-			if !*rewrite {
-				fmt.Printf("\n%s%v already exists%s\n\n", Red, outputPath, Reset)
-				//Print the prompt
-				fmt.Println("Choose an option:")
-				fmt.Println("1 - Overwrite")
-				if outputType == "txt" {
-					fmt.Printf("2 - Change Name to:%v (%d).txt\n", originalOutputPath[:len(originalOutputPath)-4], iterate)
-				} else if outputType == "html" {
-					fmt.Printf("2 - Change Name to:%v (%d).html\n", originalOutputPath[:len(originalOutputPath)-5], iterate)
-				}
-				fmt.Println("3 - Cancel")
-
-				//Scan for choice
-				_, err := fmt.Scanf("%d\n", &choice)
-
-				//Check for choice validity
-				if err != nil || (choice < 1 || choice > 3) {
-					fmt.Println("Invalid choice. Please enter 1, 2, or 3.")
-					continue
-				}
-			} else {
-				choice = 2
-			}
-
-			//Change output if user chose to
-			if choice == 2 {
-				if outputType == "txt" {
-					if iterate > 1 {
-						if iterate > 9 {
-							outputPath = outputPath[:len(outputPath)-9] + ".txt"
-						} else {
-							outputPath = outputPath[:len(outputPath)-8] + ".txt"
-						}
-					}
-					newOutputPath = outputPath[:len(outputPath)-4] + " (" + strconv.Itoa(iterate) + ").txt"
-				} else if outputType == "html" {
-					if iterate > 1 {
-						if iterate > 9 {
-							outputPath = outputPath[:len(outputPath)-10] + ".html"
-						} else {
-							outputPath = outputPath[:len(outputPath)-9] + ".html"
-						}
-					}
-					newOutputPath = outputPath[:len(outputPath)-5] + " (" + strconv.Itoa(iterate) + ").html"
-				}
-				// Check if the new output path still exists
-				if _, err := os.Stat(newOutputPath); err == nil {
-					if !*rewrite {
-						fmt.Println("File with the new name also exists. Please choose again.")
-					}
-					outputPath = newOutputPath
-					iterate++
-					continue
-				} else if !os.IsNotExist(err) {
-					fmt.Printf("Could not access file %v, option to overwrite is unavailable - %v\n", outputPath, err)
-					continue
-				}
-				outputPath = newOutputPath
-			}
-
-			//When valid choice, continue
-			fmt.Println("You chose to:", options[choice])
-			if choice == 1 || choice == 2 {
-				break
-			}
-			if choice == 3 {
-				return
-			}
-		}
+	//Store arguments for convenience
+	inputPath := convertFlags.Args()[0]
+	outputPath := convertFlags.Args()[1]
+
+	//Without any lookup the embedded one is used
+	lookupPaths := chooseLookups(convertFlags.Args()[2:])
 
+	//Load the input and check if it exists
+	if _, err := os.Stat(inputPath); err != nil {
+		report(Diagnostic{Code: diagInputMissing, Severity: severityError, File: inputPath, Message: "input not found: " + err.Error()})
+		return exitInput
 	}
 
-	if outputType == "html" {
-		//Format the input for the output as an html
-		userInput = getOutputStringHTML(userInput)
-	} else {
-		//Format the input for the output
-		userInput = getOutputString(userInput)
+	//Load the airport lookup
+	if err := loadAirports(lookupPaths...); err != nil {
+		return reportLookupError("", err)
+	}
+	if err := loadAirlines(chooseAirlines()); err != nil {
+		return reportLookupError("", err)
 	}
 
-	//Create output
-	file, err := os.Create(outputPath)
+	return convertFile(inputPath, outputPath, policy, *opts.strict)
+}
+
+// Converts one itinerary with the airports that are loaded
+func convertFile(inputPath, outputPath string, policy CollisionPolicy, strict bool) int {
+	//Load the input and check if it exists
+	userInput, err := loadFile(inputPath)
 	if err != nil {
-		fmt.Println("Error creating file: ", err)
-		return
+		report(Diagnostic{Code: diagInputMissing, Severity: severityError, File: inputPath, Message: "input not found: " + err.Error()})
+		return exitInput
 	}
-	defer file.Close()
 
-	//Write into output
-	_, err = file.WriteString(userInput)
+	//Markup, PNR dumps and JSON bookings are all read into one itinerary
+	itinerary, err := parseItinerary(inputPath, userInput, time.Now())
 	if err != nil {
-		fmt.Println("Error writing to file: ", err)
-		return
+		report(Diagnostic{Code: diagInputMalformed, Severity: severityError, File: inputPath, Message: err.Error()})
+		return exitInput
 	}
 
-	println(outputPath, " created succesfully")
+	//Check for type of output
+	outputRenderer := rendererFor(outputFormatOf(outputPath), time.Now())
 
-	//Testing tools
-	if len(os.Args) > 4 {
-		if os.Args[4] == "test" {
-			switch os.Args[5] {
-			case "string":
+	//Look for airport codes missing from the lookup before anything is written
+	unresolved := findUnresolvedInItinerary(itinerary)
+	if strict && len(unresolved) > 0 {
+		reportUnresolvedCodes(inputPath, unresolved, severityError)
+		return exitProblems
+	}
 
-				// Testing func validString
-				print(airports[24].Name + " ")
-				println(validString(airports[24].Name))
+	//Settle what happens when the output already exists
+	requestedPath := outputPath
+	outputPath, err = resolveOutputPath(policy, outputPath)
+	if errors.Is(err, errCollisionCancelled) {
+		return exitOK
+	} else if err != nil {
+		report(Diagnostic{Code: diagFileCollision, Severity: severityError, File: requestedPath, Message: err.Error()})
+		return exitOutput
+	}
 
-			case "input":
+	//Format the itinerary for the output
+	userInput = outputRenderer.render(itinerary)
 
-				// Testing an input
-				println(userInput)
+	//Legacy systems only take ASCII
+	if asciiOutput {
+		userInput = transliterate(userInput)
+	}
 
-			}
-			return
+	//Keep the version that is about to be replaced
+	if backupMode != "" {
+		backup, err := backupFile(outputPath, backupMode, time.Now())
+		if err != nil {
+			report(Diagnostic{Code: diagOutputFailed, Severity: severityError, File: outputPath, Message: err.Error()})
+			return exitOutput
+		}
+		if backup != "" {
+			report(Diagnostic{Code: diagFileCollision, Severity: severityInfo, File: outputPath, Message: "previous version kept as " + backup})
 		}
 	}
+
+	//Write into output, a failed write leaves the previous file untouched
+	if err := writeFileAtomic(outputPath, []byte(userInput)); err != nil {
+		report(Diagnostic{Code: diagOutputFailed, Severity: severityError, File: outputPath, Message: "error writing to file: " + err.Error()})
+		return exitOutput
+	}
+
+	fmt.Println(outputPath, "created succesfully")
+
+	//Report the codes that were left unchanged
+	reportUnresolvedCodes(inputPath, unresolved, severityWarning)
+	return exitOK
 }
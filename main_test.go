@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidString(t *testing.T) {
+	valid := []string{"London Heathrow Airport", "Zürich Airport", "São Paulo–Guarulhos"}
+	for _, name := range valid {
+		if !validString(name) {
+			t.Errorf("validString(%q) = false, want true", name)
+		}
+	}
+	invalid := []string{"Bad \xff byte", "Broken � conversion", "Tab\tinside"}
+	for _, name := range invalid {
+		if validString(name) {
+			t.Errorf("validString(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestLoadedNamesAreValid(t *testing.T) {
+	loadTestLookup(t, embeddedLookupPath)
+	if len(airports) == 0 {
+		t.Fatal("no airports loaded from the embedded lookup")
+	}
+	for _, airport := range airports {
+		if !validString(airport.Name) || !validString(airport.Municipality) {
+			t.Errorf("airport %v %v was loaded with an invalid name %q", airport.IATA_Code, airport.ICAO_Code, airport.Name)
+		}
+	}
+}
+
+func TestConvertInput(t *testing.T) {
+	loadTestLookup(t, embeddedLookupPath)
+	output := convertMarkup(t, "Flight from #LHR to ##EGLL\nDeparts D(2023-06-15T14:00+01:00)\n\n\n\nat T24(2023-06-15T14:00+01:00)", "txt")
+	for _, want := range []string{"London Heathrow Airport", "15 Jun 2023", "at 14:00"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output %q does not contain %q", output, want)
+		}
+	}
+	if strings.Contains(output, "\n\n\n") {
+		t.Errorf("output %q keeps more than one blank line", output)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, []byte("Flight from #LHR\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { diagnostics = nil })
+
+	tests := []struct {
+		args []string
+		exit int
+	}{
+		{[]string{"lookup"}, exitUsage},
+		{[]string{"completion", "tcsh"}, exitUsage},
+		{[]string{"convert", "-o", filepath.Join(dir, "missing.txt"), filepath.Join(dir, "out.txt")}, exitInput},
+		{[]string{"-o", input, filepath.Join(dir, "out.txt")}, exitOK},
+		{[]string{"convert", "--overwrite", input, filepath.Join(dir, "out.txt")}, exitOK},
+	}
+	for _, test := range tests {
+		if exit := runCommand(test.args); exit != test.exit {
+			t.Errorf("runCommand(%q) = %d, want %d", test.args, exit, test.exit)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.txt")); err != nil {
+		t.Errorf("convert wrote no output: %v", err)
+	}
+}
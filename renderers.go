@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Formats an itinerary can be written as, chosen by the extension of the output
+var outputFormats = []string{"txt", "html", "json", "ics"}
+
+// Writes an itinerary in one output format
+type renderer interface {
+	render(read Itinerary) string
+}
+
+// now is the DTSTAMP of the calendar events
+func rendererFor(format string, now time.Time) renderer {
+	switch format {
+	case "html":
+		return htmlRenderer{}
+	case "json":
+		return jsonRenderer{}
+	case "ics":
+		return icsRenderer{stamp: now}
+	}
+	return textRenderer{}
+}
+
+// Anything but .html, .json and .ics is written as text
+func outputFormatOf(path string) string {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if containsWord(outputFormats, format) {
+		return format
+	}
+	return "txt"
+}
+
+// The paragraphs of an itinerary in the order of the input, written text is prettified as
+// markup and the segments of a booking are written from their fields
+func renderParagraphs(read Itinerary, written func(text string) string, segment func(segment Segment) string) string {
+	var paragraphs []string
+	var header []string
+	if read.Reference != "" {
+		header = append(header, "Booking reference: "+read.Reference)
+	}
+	if len(read.Passengers) > 0 {
+		header = append(header, "Passengers: "+strings.Join(read.Passengers, ", "))
+	}
+	if len(header) > 0 {
+		paragraphs = append(paragraphs, strings.Join(header, "\n"))
+	}
+
+	read.eachParagraph(func(text string, line int, s *Segment) {
+		if text != "" {
+			paragraphs = append(paragraphs, written(text))
+		} else {
+			paragraphs = append(paragraphs, segment(*s))
+		}
+	})
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// How an output writes the airline, the airports and the times of a segment
+type segmentStyle struct {
+	airline func(airline Airline) string
+	airport func(airport Airport) string
+	time    func(stamp timeStamp, text string, shift int) string
+}
+
+// A segment of a booking as the markup of a flight would read
+func renderSegment(segment Segment, style segmentStyle) string {
+	title := "Flight"
+	if airline, _, found := findAirlineByToken(segment.Airline + segment.Number); found {
+		title += " " + style.airline(airline) + " " + segment.Number
+	} else if segment.Airline != "" {
+		//An unknown airline is left as it was written, like in markup
+		title += " " + segment.Airline + segment.Number
+	}
+	if segment.Class != "" {
+		title += ", class " + segment.Class
+	}
+	if segment.Status != "" {
+		title += ", " + segment.Status
+	}
+
+	place := func(code string, airport Airport) string {
+		if airport.Name == "" {
+			return code
+		}
+		return style.airport(airport)
+	}
+	lines := []string{title, "From " + place(segment.From, segment.Origin) + " to " + place(segment.To, segment.Destination)}
+
+	//Local times have no offset to write, the booking gives their day shift
+	if segment.LocalOnly {
+		lines = append(lines, "Departs "+style.time(timeStamp{kind: "D"}, formatDate(segment.Departure), 0)+" "+
+			style.time(timeStamp{kind: "T24"}, formatClock24(segment.Departure), 0))
+		if !segment.Arrival.IsZero() {
+			lines = append(lines, "Arrives "+style.time(timeStamp{kind: "T24"}, formatClock24(segment.Arrival), segment.DayShift))
+		}
+		return strings.Join(lines, "\n")
+	}
+	date, clock := stampOf("D", segment.Departure), stampOf("T24", segment.Departure)
+	lines = append(lines, "Departs "+style.time(date, formatTimeStamp(date), 0)+" "+style.time(clock, formatTimeStamp(clock), 0))
+	if !segment.Arrival.IsZero() {
+		arrival := stampOf("T24", segment.Arrival)
+		lines = append(lines, "Arrives "+style.time(arrival, formatTimeStamp(arrival), segment.DayShift))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// The codes and tokens replaced, notes and all
+type textRenderer struct{}
+
+var textStyle = segmentStyle{
+	airline: airlineText,
+	airport: func(airport Airport) string { return airport.Name },
+	time:    timeText,
+}
+
+func (textRenderer) render(read Itinerary) string {
+	total := read.totalDistance()
+	output := renderParagraphs(read, func(text string) string {
+		return formatMarkup(text, total)
+	}, func(segment Segment) string {
+		return renderSegment(segment, textStyle)
+	})
+	return cleanUpDoubleWhiteSpaces(replaceLineBreaks(output))
+}
+
+type htmlRenderer struct{}
+
+var htmlStyle = segmentStyle{
+	airline: airlineHTML,
+	airport: airportLinkHTML,
+	time:    timeHTML,
+}
+
+func (htmlRenderer) render(read Itinerary) string {
+	total := read.totalDistance()
+	body := renderParagraphs(read, func(text string) string {
+		return formatMarkupHTML(text, total)
+	}, func(segment Segment) string {
+		return renderSegment(segment, htmlStyle)
+	})
+	//The last paragraph ends with a line like the markup does
+	return htmlPage(body+"\n", buildRouteMap(read.route()))
+}
+
+// The segments with their airports looked up, for other systems to read
+type jsonRenderer struct{}
+
+type jsonItinerary struct {
+	Reference  string              `json:"reference,omitempty"`
+	Passengers []string            `json:"passengers,omitempty"`
+	Segments   []jsonOutputSegment `json:"segments"`
+	Notes      []string            `json:"notes,omitempty"`
+}
+
+type jsonOutputSegment struct {
+	Flight    string            `json:"flight,omitempty"` //BA283
+	Airline   string            `json:"airline,omitempty"`
+	Class     string            `json:"class,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	From      jsonOutputAirport `json:"from"`
+	To        jsonOutputAirport `json:"to"`
+	Departure string            `json:"departure"` //2023-06-15T14:00-07:00, without an offset when only local
+	Arrival   string            `json:"arrival,omitempty"`
+	DayShift  int               `json:"day_shift,omitempty"`
+}
+
+type jsonOutputAirport struct {
+	Code    string `json:"code"` //LAX or KLAX
+	Name    string `json:"name,omitempty"`
+	City    string `json:"city,omitempty"`
+	Country string `json:"country,omitempty"`
+}
+
+func newJSONOutputAirport(code string, airport Airport) jsonOutputAirport {
+	return jsonOutputAirport{Code: strings.TrimLeft(code, "#"), Name: airport.Name, City: airport.Municipality, Country: airport.ISO_Country}
+}
+
+// The layouts the JSON booking input reads
+func formatSegmentTime(instant time.Time, localOnly bool) string {
+	if instant.IsZero() {
+		return ""
+	}
+	if localOnly {
+		return instant.Format("2006-01-02T15:04")
+	}
+	return instant.Format("2006-01-02T15:04Z07:00")
+}
+
+func (jsonRenderer) render(read Itinerary) string {
+	out := jsonItinerary{Reference: read.Reference, Passengers: read.Passengers, Segments: []jsonOutputSegment{}}
+	for _, segment := range read.Segments {
+		airline, _ := segment.airlineName()
+		out.Segments = append(out.Segments, jsonOutputSegment{
+			Flight:    segment.flight(),
+			Airline:   airline,
+			Class:     segment.Class,
+			Status:    segment.Status,
+			From:      newJSONOutputAirport(segment.From, segment.Origin),
+			To:        newJSONOutputAirport(segment.To, segment.Destination),
+			Departure: formatSegmentTime(segment.Departure, segment.LocalOnly),
+			Arrival:   formatSegmentTime(segment.Arrival, segment.LocalOnly),
+			DayShift:  segment.DayShift,
+		})
+	}
+	//Notes are prettified like the text output, DIST(TOTAL) is the whole trip
+	total := read.totalDistance()
+	for _, note := range read.Notes {
+		out.Notes = append(out.Notes, cleanUpDoubleWhiteSpaces(formatMarkup(note.Text, total)))
+	}
+
+	encoded, _ := json.MarshalIndent(out, "", "  ")
+	return string(encoded) + "\n"
+}
+
+// One calendar event per flight, times with an offset in UTC and local ones floating
+type icsRenderer struct {
+	stamp time.Time
+}
+
+// Content lines are at most 75 octets, longer ones go on with a space
+const icsLineLength = 75
+
+func icsText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(value)
+}
+
+func icsTime(instant time.Time, localOnly bool) string {
+	if localOnly {
+		return instant.Format("20060102T150405")
+	}
+	return instant.UTC().Format("20060102T150405Z")
+}
+
+// Folds a line without cutting a character in two
+func icsFold(line string) string {
+	var folded strings.Builder
+	size := 0
+	for _, r := range line {
+		width := len(string(r))
+		if size+width > icsLineLength {
+			folded.WriteString("\r\n ")
+			size = 1
+		}
+		folded.WriteRune(r)
+		size += width
+	}
+	return folded.String()
+}
+
+// The city of an airport, the code when the lookup doesn't know it
+func placeName(code string, airport Airport) string {
+	if airport.Municipality != "" {
+		return airport.Municipality
+	}
+	return code
+}
+
+func (renderer icsRenderer) render(read Itinerary) string {
+	var lines []string
+	add := func(name, value string) {
+		lines = append(lines, icsFold(name+":"+value))
+	}
+
+	add("BEGIN", "VCALENDAR")
+	add("VERSION", "2.0")
+	add("PRODID", "-//Anywhere Holidays//Itinerary Prettifier//EN")
+	add("CALSCALE", "GREGORIAN")
+	for i, segment := range read.Segments {
+		flight := "Flight"
+		if name, found := segment.airlineName(); found {
+			flight = name + " " + segment.Number
+		} else if segment.Airline != "" {
+			flight = "Flight " + segment.flight()
+		}
+
+		var details []string
+		if segment.Class != "" {
+			details = append(details, "Class "+segment.Class)
+		}
+		if segment.Status != "" {
+			details = append(details, "Status: "+segment.Status)
+		}
+		if len(read.Passengers) > 0 {
+			details = append(details, "Passengers: "+strings.Join(read.Passengers, ", "))
+		}
+		if read.Reference != "" {
+			details = append(details, "Booking reference: "+read.Reference)
+		}
+
+		add("BEGIN", "VEVENT")
+		add("UID", fmt.Sprintf("%v-%d-%v@itinerary-prettifier", icsTime(segment.Departure, segment.LocalOnly), i+1, strings.TrimLeft(segment.From, "#")))
+		add("DTSTAMP", icsTime(renderer.stamp, false))
+		add("DTSTART", icsTime(segment.Departure, segment.LocalOnly))
+		//Local times of a short westbound flight can end before they start, those events have no end
+		if segment.Arrival.After(segment.Departure) {
+			add("DTEND", icsTime(segment.Arrival, segment.LocalOnly))
+		}
+		add("SUMMARY", icsText(fmt.Sprintf("%v from %v to %v", flight,
+			placeName(segment.From, segment.Origin), placeName(segment.To, segment.Destination))))
+		if segment.Origin.Name != "" {
+			add("LOCATION", icsText(segment.Origin.Name))
+		}
+		if len(details) > 0 {
+			add("DESCRIPTION", icsText(strings.Join(details, "\n")))
+		}
+		add("END", "VEVENT")
+	}
+	add("END", "VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The DTSTAMP of the calendar goldens
+var renderStamp = time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+
+func TestRenderGolden(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	defer func() { diagnostics = nil }()
+	inputs := []string{
+		filepath.Join("testdata", "itineraries", "basic.txt"),
+		filepath.Join("testdata", "itineraries", "overnight.txt"),
+		filepath.Join("testdata", "bookings", "trip.json"),
+		filepath.Join("testdata", "bookings", "trip.pnr"),
+	}
+	for _, inputPath := range inputs {
+		content, err := os.ReadFile(inputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read, err := parseItinerary(inputPath, string(content), bookingNow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := strings.TrimSuffix(filepath.Base(inputPath), ".txt")
+		for _, format := range []string{"json", "ics"} {
+			t.Run(name+"."+format, func(t *testing.T) {
+				checkGolden(t, name+"."+format, rendererFor(format, renderStamp).render(read))
+			})
+		}
+	}
+}
+
+// DIST(TOTAL) in a note is the whole trip, not the note on its own
+func TestNoteDistance(t *testing.T) {
+	loadTestLookup(t, goldenLookup)
+	read := itineraryFromMarkup("From #LAX to #LHR on T24(2023-06-15T14:00-07:00)\n\n" +
+		"From #LHR to #LAX on T24(2023-06-25T10:15+01:00)\n\n" +
+		"You fly DIST(TOTAL) in all.")
+	want := "You fly 17519 km in all."
+	if got := (textRenderer{}).render(read); !strings.HasSuffix(got, want) {
+		t.Errorf("text output ends %q, want %q", got[strings.LastIndex(got, "\n")+1:], want)
+	}
+	if got := (jsonRenderer{}).render(read); !strings.Contains(got, `"`+want+`"`) {
+		t.Errorf("json output %v, want the note %q", got, want)
+	}
+}
+
+// Markup without times has no segments, its codes are still the route
+func TestMarkupRoute(t *testing.T) {
+	loadTestLookup(t, embeddedLookupPath)
+	read := itineraryFromMarkup("Flight from #LAX to #JFK\nThen from #JFK to #LHR\nTotal: DIST(TOTAL)")
+	var codes []string
+	for _, airport := range read.route() {
+		codes = append(codes, airport.IATA_Code)
+	}
+	if strings.Join(codes, " ") != "LAX JFK LHR" {
+		t.Errorf("route = %v, want LAX JFK LHR", codes)
+	}
+	if got := (textRenderer{}).render(read); !strings.HasSuffix(got, "Total: 9514 km") {
+		t.Errorf("text output %q, want it to end with Total: 9514 km", got)
+	}
+	if got := (htmlRenderer{}).render(read); !strings.Contains(got, "<svg") || !strings.Contains(got, "Total: 9514 km") {
+		t.Errorf("html output has no route map or total: %v", got)
+	}
+}
+
+func TestOutputFormatOf(t *testing.T) {
+	for path, want := range map[string]string{
+		"trip.txt":  "txt",
+		"trip.HTML": "html",
+		"trip.json": "json",
+		"trip.ics":  "ics",
+		"trip.md":   "txt",
+		"trip":      "txt",
+	} {
+		if got := outputFormatOf(path); got != want {
+			t.Errorf("outputFormatOf(%v) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestICSFold(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("é", 60)
+	for _, folded := range strings.Split(icsFold(line), "\r\n") {
+		if len(folded) > icsLineLength {
+			t.Errorf("folded line is %d octets: %q", len(folded), folded)
+		}
+	}
+	if got := strings.ReplaceAll(icsFold(line), "\r\n ", ""); got != line {
+		t.Errorf("unfolded line = %q, want %q", got, line)
+	}
+	if got := icsText("Smith, John; A\\B\nC"); got != `Smith\, John\; A\\B\nC` {
+		t.Errorf("icsText = %q", got)
+	}
+}
+
+// Names from a lookup are text in the html, whatever characters they have
+func TestHTMLEscaping(t *testing.T) {
+	defer func() { airports, airlines = nil, nil }()
+	airports = []Airport{
+		{Name: "Elizabeth City Regional Airport & Coast Guard Air Station", Municipality: "Elizabeth <City>", ISO_Country: "US",
+			IATA_Code: "ECG", ICAO_Code: "KECG", Latitude: 36.26, Longitude: -76.17},
+		{Name: "Bill & Hillary Clinton National Airport", Municipality: "Little Rock", ISO_Country: "US",
+			IATA_Code: "LIT", ICAO_Code: "KLIT", Latitude: 34.73, Longitude: -92.22},
+	}
+	airlines = []Airline{{Name: "<script>alert(1)</script> Air", IATA_Code: "XA", ICAO_Code: "XAA", Website: "https://example.com/?a=1&b=\"2\""}}
+
+	read := itineraryFromMarkup("Flight @XA100 from #ECG to ##KLIT\nVia *#ECG and ^^#LIT\n\n" +
+		"Then from #LIT to #ECG on T24(2023-06-15T14:00-05:00)")
+	html := (htmlRenderer{}).render(read)
+	for _, unwanted := range []string{"<script>", "<City>", "Airport & Coast", "Bill & Hillary", "b=\"2\""} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("html contains %q unescaped", unwanted)
+		}
+	}
+	for _, want := range []string{
+		`<a href="https://example.com/?a=1&amp;b=&quot;2&quot;" target="_blank">&lt;script&gt;alert(1)&lt;/script&gt; Air</a> 100`,
+		`<a href="https://www.google.com/maps/search/?api=1&amp;query=Bill+%26+Hillary+Clinton+National+Airport" target="_blank">Bill &amp; Hillary Clinton National Airport</a>`,
+		`Via Elizabeth &lt;City&gt; and Bill &amp; Hillary Clinton National Airport, Little Rock, United States`,
+		`<title>Elizabeth City Regional Airport &amp; Coast Guard Air Station</title>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html is missing %q", want)
+		}
+	}
+
+	//The text output keeps the names as they are
+	text := (textRenderer{}).render(read)
+	if !strings.Contains(text, "Via Elizabeth <City> and Bill & Hillary Clinton National Airport") {
+		t.Errorf("text output %q changed the names", text)
+	}
+}
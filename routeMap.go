@@ -0,0 +1,140 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size of the route map drawn into the HTML output
+const (
+	mapWidth  = 540.0
+	mapHeight = 270.0
+	arcSteps  = 48
+)
+
+func findAirportByIATA(code string) (Airport, bool) {
+	for _, airport := range airports {
+		if airport.IATA_Code == code {
+			return airport, true
+		}
+	}
+	return Airport{}, false
+}
+
+func findAirportByICAO(code string) (Airport, bool) {
+	for _, airport := range airports {
+		if airport.ICAO_Code == code {
+			return airport, true
+		}
+	}
+	return Airport{}, false
+}
+
+func projectPoint(lat, lon float64) (float64, float64) {
+	//Plain equirectangular projection
+	x := (lon + 180) / 360 * mapWidth
+	y := (90 - lat) / 180 * mapHeight
+	return x, y
+}
+
+func formatPoint(x, y float64) string {
+	return strconv.FormatFloat(x, 'f', 1, 64) + "," + strconv.FormatFloat(y, 'f', 1, 64)
+}
+
+func greatCircleArc(lat1, lon1, lat2, lon2 float64) [][2]float64 {
+	//Convert both ends into unit vectors
+	toVector := func(lat, lon float64) [3]float64 {
+		phi, lambda := lat*math.Pi/180, lon*math.Pi/180
+		return [3]float64{math.Cos(phi) * math.Cos(lambda), math.Cos(phi) * math.Sin(lambda), math.Sin(phi)}
+	}
+	a := toVector(lat1, lon1)
+	b := toVector(lat2, lon2)
+
+	dot := a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+	angle := math.Acos(math.Max(-1, math.Min(1, dot)))
+	if angle == 0 {
+		return [][2]float64{{lat1, lon1}}
+	}
+
+	//Spherical interpolation between the two ends
+	var points [][2]float64
+	for i := 0; i <= arcSteps; i++ {
+		t := float64(i) / arcSteps
+		wa := math.Sin((1-t)*angle) / math.Sin(angle)
+		wb := math.Sin(t*angle) / math.Sin(angle)
+		x := wa*a[0] + wb*b[0]
+		y := wa*a[1] + wb*b[1]
+		z := wa*a[2] + wb*b[2]
+		lat := math.Atan2(z, math.Sqrt(x*x+y*y)) * 180 / math.Pi
+		lon := math.Atan2(y, x) * 180 / math.Pi
+		points = append(points, [2]float64{lat, lon})
+	}
+	return points
+}
+
+func arcPath(points [][2]float64) string {
+	var path strings.Builder
+	for i, point := range points {
+		x, y := projectPoint(point[0], point[1])
+		//Start a new piece of the line when the arc wraps around the antimeridian
+		if i == 0 || math.Abs(point[1]-points[i-1][1]) > 180 {
+			path.WriteString("M")
+		} else {
+			path.WriteString("L")
+		}
+		path.WriteString(formatPoint(x, y))
+	}
+	return path.String()
+}
+
+func worldOutlinePath() string {
+	var path strings.Builder
+	for _, polygon := range worldOutline {
+		for i, point := range polygon {
+			x, y := projectPoint(point[1], point[0])
+			if i == 0 {
+				path.WriteString("M")
+			} else {
+				path.WriteString("L")
+			}
+			path.WriteString(formatPoint(x, y))
+		}
+		path.WriteString("Z")
+	}
+	return path.String()
+}
+
+func escapeHTML(input string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;")
+	return replacer.Replace(input)
+}
+
+func buildRouteMap(route []Airport) string {
+	//A single airport is no route
+	if len(route) < 2 {
+		return ""
+	}
+
+	var svg strings.Builder
+	svg.WriteString("<svg xmlns=\"http://www.w3.org/2000/svg\" viewBox=\"0 0 " +
+		strconv.Itoa(int(mapWidth)) + " " + strconv.Itoa(int(mapHeight)) + "\" width=\"100%\" role=\"img\" aria-label=\"Route map\">")
+	svg.WriteString("<rect width=\"100%\" height=\"100%\" fill=\"#dbeaf7\"/>")
+	svg.WriteString("<path d=\"" + worldOutlinePath() + "\" fill=\"#c9d3c0\" stroke=\"#aab5a0\" stroke-width=\"0.5\"/>")
+
+	//Draw every leg as a great-circle arc
+	for i := 1; i < len(route); i++ {
+		from, to := route[i-1], route[i]
+		svg.WriteString("<path d=\"" + arcPath(greatCircleArc(from.Latitude, from.Longitude, to.Latitude, to.Longitude)) + "\" fill=\"none\" stroke=\"#007bff\" stroke-width=\"2\"/>")
+	}
+
+	//Mark the airports on top of the lines
+	for _, airport := range route {
+		x, y := projectPoint(airport.Latitude, airport.Longitude)
+		svg.WriteString("<circle cx=\"" + strconv.FormatFloat(x, 'f', 1, 64) + "\" cy=\"" + strconv.FormatFloat(y, 'f', 1, 64) +
+			"\" r=\"3.5\" fill=\"#ffffff\" stroke=\"#007bff\" stroke-width=\"2\"><title>" + escapeHTML(airport.Name) + "</title></circle>")
+	}
+	svg.WriteString("</svg>")
+
+	return "<p style=\"text-align: center;\">" + svg.String() + "</p>"
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestGreatCircleArc(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+	}{
+		{"LHR to LAX", 51.4706, -0.461941, 33.942501, -118.407997},
+		{"across the antimeridian", 35.552299, 139.779999, 21.318701, -157.921997},
+		{"along the equator", 0, 10, 0, 20},
+	}
+	for _, test := range tests {
+		points := greatCircleArc(test.lat1, test.lon1, test.lat2, test.lon2)
+		if len(points) != arcSteps+1 {
+			t.Errorf("%v: %v points, want %v", test.name, len(points), arcSteps+1)
+			continue
+		}
+		first, last := points[0], points[len(points)-1]
+		if math.Abs(first[0]-test.lat1) > 1e-9 || math.Abs(first[1]-test.lon1) > 1e-9 {
+			t.Errorf("%v: starts at %v, want %v,%v", test.name, first, test.lat1, test.lon1)
+		}
+		if math.Abs(last[0]-test.lat2) > 1e-9 || math.Abs(last[1]-test.lon2) > 1e-9 {
+			t.Errorf("%v: ends at %v, want %v,%v", test.name, last, test.lat2, test.lon2)
+		}
+	}
+
+	//The same place twice is a single point
+	if points := greatCircleArc(10, 20, 10, 20); len(points) != 1 {
+		t.Errorf("an arc to the same place has %v points, want 1", len(points))
+	}
+}
+
+func TestArcPathAntimeridian(t *testing.T) {
+	//Tokyo to Honolulu crosses the antimeridian, so the line is drawn in two pieces
+	path := arcPath(greatCircleArc(35.552299, 139.779999, 21.318701, -157.921997))
+	if got := strings.Count(path, "M"); got != 2 {
+		t.Errorf("Tokyo to Honolulu has %v pieces, want 2: %v", got, path)
+	}
+	path = arcPath(greatCircleArc(51.4706, -0.461941, 33.942501, -118.407997))
+	if got := strings.Count(path, "M"); got != 1 {
+		t.Errorf("London to Los Angeles has %v pieces, want 1: %v", got, path)
+	}
+}
+
+func TestBuildRouteMap(t *testing.T) {
+	heathrow := Airport{Name: "London Heathrow Airport", Latitude: 51.4706, Longitude: -0.461941}
+	losAngeles := Airport{Name: "Los Angeles International Airport", Latitude: 33.942501, Longitude: -118.407997}
+
+	if got := buildRouteMap(nil); got != "" {
+		t.Errorf("no airports gave a map: %v", got)
+	}
+	if got := buildRouteMap([]Airport{heathrow}); got != "" {
+		t.Errorf("a single airport gave a map: %v", got)
+	}
+
+	got := buildRouteMap([]Airport{heathrow, losAngeles, heathrow})
+	if !strings.Contains(got, "<svg") {
+		t.Fatalf("no map for three airports: %v", got)
+	}
+	//One arc per leg and one marker per stop
+	if arcs := strings.Count(got, "fill=\"none\""); arcs != 2 {
+		t.Errorf("%v arcs, want 2", arcs)
+	}
+	if markers := strings.Count(got, "<circle"); markers != 3 {
+		t.Errorf("%v markers, want 3", markers)
+	}
+	if !strings.Contains(got, "<title>Los Angeles International Airport</title>") {
+		t.Errorf("the markers are not named after the airports: %v", got)
+	}
+}
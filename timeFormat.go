@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Set with --offset-style, how the offset after a time is written
+var offsetStyle = "iso"
+
+var offsetStyles = map[string]bool{
+	"iso": true, //(+05:30)
+	"utc": true, //(UTC+5:30)
+	"gmt": true, //(GMT+5:30)
+}
+
+// Offsets used anywhere in the world, in minutes east of UTC
+const (
+	minOffset = -12 * 60
+	maxOffset = 14 * 60
+)
+
+// A D(...), T12(...) or T24(...) token read into a time in its own offset
+type timeStamp struct {
+	kind    string //D, T12 or T24
+	instant time.Time
+	offset  int //Minutes east of UTC
+}
+
+func tokenInstant(match string) (time.Time, error) {
+	//Cut the value out of D(...), T12(...) or T24(...)
+	value := match[strings.Index(match, "(")+1 : len(match)-1]
+	value = strings.ReplaceAll(value, "−", "-")
+	return time.Parse("2006-01-02T15:04Z07:00", value)
+}
+
+func validOffset(minutes int) bool {
+	return minutes >= minOffset && minutes <= maxOffset
+}
+
+// Reads a token matched by timeToken, fails on impossible dates and offsets
+func parseTimeToken(match string) (timeStamp, error) {
+	instant, err := tokenInstant(match)
+	if err != nil {
+		return timeStamp{}, err
+	}
+	_, seconds := instant.Zone()
+	if !validOffset(seconds / 60) {
+		return timeStamp{}, fmt.Errorf("offset out of range in %v, it must be between -12:00 and +14:00", match)
+	}
+	return timeStamp{kind: match[:strings.Index(match, "(")], instant: instant, offset: seconds / 60}, nil
+}
+
+// 15 Jun 2023
+func formatDate(instant time.Time) string {
+	return instant.Format("02 Jan 2006")
+}
+
+// 12:00AM is midnight and 12:00PM noon
+func formatClock12(instant time.Time) string {
+	suffix := "AM"
+	if instant.Hour() >= 12 {
+		suffix = "PM"
+	}
+	hours := instant.Hour() % 12
+	if hours == 0 {
+		hours = 12
+	}
+	return fmt.Sprintf("%02d:%02d%v", hours, instant.Minute(), suffix)
+}
+
+func formatClock24(instant time.Time) string {
+	return instant.Format("15:04")
+}
+
+// Always signed: +05:30 in the iso style, UTC+5:30 or GMT-7 in the others
+func formatOffset(minutes int, style string) string {
+	sign := "+"
+	if minutes < 0 {
+		sign = "-"
+		minutes = -minutes
+	}
+	hours, rest := minutes/60, minutes%60
+
+	if style == "iso" {
+		return fmt.Sprintf("%v%02d:%02d", sign, hours, rest)
+	}
+	prefix := strings.ToUpper(style)
+	if minutes == 0 {
+		return prefix
+	}
+	if rest == 0 {
+		return fmt.Sprintf("%v%v%d", prefix, sign, hours)
+	}
+	return fmt.Sprintf("%v%v%d:%02d", prefix, sign, hours, rest)
+}
+
+// A time of a segment as the token that would have written it
+func stampOf(kind string, instant time.Time) timeStamp {
+	_, seconds := instant.Zone()
+	return timeStamp{kind: kind, instant: instant, offset: seconds / 60}
+}
+
+// The text a token is replaced with, shared by the text and html output
+func formatTimeStamp(stamp timeStamp) string {
+	switch stamp.kind {
+	case "D":
+		return formatDate(stamp.instant)
+	case "T12":
+		return formatClock12(stamp.instant) + " (" + formatOffset(stamp.offset, offsetStyle) + ")"
+	}
+	return formatClock24(stamp.instant) + " (" + formatOffset(stamp.offset, offsetStyle) + ")"
+}
+
+// Arrivals more days away than this belong to another trip, not to an overnight flight
+const maxDayShift = 2
+
+// A blank line ends a segment, a time after it starts a new one
+var paragraphBreak = regexp.MustCompile(`\n[ \t]*\n`)
+
+// Calendar days from one local date to another, the offsets of both are kept
+func calendarDays(from, to time.Time) int {
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDate.Sub(fromDate).Hours() / 24)
+}
+
+// How many days every time lands after the date or time before it in the same paragraph,
+// +1 for an overnight arrival and -1 across the date line, keyed by where the token starts
+func dayShifts(input string) map[int]int {
+	shifts := map[int]int{}
+	var previous *timeStamp
+	previousEnd := 0
+	for _, loc := range timeToken.FindAllStringIndex(input, -1) {
+		stamp, err := parseTimeToken(input[loc[0]:loc[1]])
+		if err != nil {
+			continue
+		}
+		if previous != nil && paragraphBreak.MatchString(replaceLineBreaks(input[previousEnd:loc[0]])) {
+			previous = nil
+		}
+		if previous != nil && stamp.kind != "D" {
+			shift := calendarDays(previous.instant, stamp.instant)
+			if shift != 0 && shift >= -maxDayShift && shift <= maxDayShift {
+				shifts[loc[0]] = shift
+			}
+		}
+		previous = &stamp
+		previousEnd = loc[1]
+	}
+	return shifts
+}
+
+// +1 or -1 as boarding passes write it
+func formatDayShift(shift int) string {
+	return fmt.Sprintf("%+d", shift)
+}
+
+// Replaces every valid token, wrap marks up dates, times and day shifts for each output
+func placeTimeTokens(input string, wrap func(stamp timeStamp, text string, shift int) string) string {
+	shifts := dayShifts(input)
+	return replaceMatches(timeToken, input, func(match string, start, end int) string {
+		stamp, err := parseTimeToken(match)
+		if err != nil {
+			//check reports why, the output keeps the token
+			return match
+		}
+		return wrap(stamp, formatTimeStamp(stamp), shifts[start])
+	})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatClock12(t *testing.T) {
+	tests := []struct {
+		hours, minutes int
+		want           string
+	}{
+		{0, 0, "12:00AM"},
+		{0, 30, "12:30AM"},
+		{1, 5, "01:05AM"},
+		{11, 59, "11:59AM"},
+		{12, 0, "12:00PM"},
+		{12, 45, "12:45PM"},
+		{13, 0, "01:00PM"},
+		{23, 59, "11:59PM"},
+	}
+	for _, test := range tests {
+		instant := time.Date(2023, 6, 15, test.hours, test.minutes, 0, 0, time.UTC)
+		if got := formatClock12(instant); got != test.want {
+			t.Errorf("formatClock12(%02d:%02d) = %q, want %q", test.hours, test.minutes, got, test.want)
+		}
+	}
+}
+
+func TestFormatOffset(t *testing.T) {
+	tests := []struct {
+		minutes int
+		style   string
+		want    string
+	}{
+		{0, "iso", "+00:00"},
+		{9 * 60, "iso", "+09:00"},
+		{-9 * 60, "iso", "-09:00"},
+		{5*60 + 30, "iso", "+05:30"},
+		{-(3*60 + 30), "iso", "-03:30"},
+		{14 * 60, "iso", "+14:00"},
+		{5*60 + 30, "utc", "UTC+5:30"},
+		{-7 * 60, "utc", "UTC-7"},
+		{0, "utc", "UTC"},
+		{-7 * 60, "gmt", "GMT-7"},
+		{5*60 + 45, "gmt", "GMT+5:45"},
+		{0, "gmt", "GMT"},
+	}
+	for _, test := range tests {
+		if got := formatOffset(test.minutes, test.style); got != test.want {
+			t.Errorf("formatOffset(%d, %v) = %q, want %q", test.minutes, test.style, got, test.want)
+		}
+	}
+}
+
+func TestOffsetStyles(t *testing.T) {
+	defer func() { offsetStyle = "iso" }()
+	tests := []struct {
+		style string
+		input string
+		want  string
+	}{
+		{"iso", "T12(2023-06-15T14:00+05:30)", "02:00PM (+05:30)"},
+		{"utc", "T12(2023-06-15T14:00+05:30)", "02:00PM (UTC+5:30)"},
+		{"gmt", "T24(2023-06-15T14:00-07:00)", "14:00 (GMT-7)"},
+		{"gmt", "D(2023-06-15T14:00-07:00)", "15 Jun 2023"},
+	}
+	for _, test := range tests {
+		offsetStyle = test.style
+		if got := placeTimes(test.input); got != test.want {
+			t.Errorf("placeTimes(%q) with %v = %q, want %q", test.input, test.style, got, test.want)
+		}
+		if got := placeTimesHTML(test.input); got != "<em>"+test.want+"</em>" && got != "<strong>"+test.want+"</strong>" {
+			t.Errorf("placeTimesHTML(%q) with %v = %q, want %q", test.input, test.style, got, test.want)
+		}
+	}
+}
+
+func TestParseTimeTokenRange(t *testing.T) {
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{"T24(2023-06-15T14:00-12:00)", true},
+		{"T24(2023-06-15T14:00+14:00)", true},
+		{"T24(2023-06-15T14:00-12:30)", false},
+		{"T24(2023-06-15T14:00+14:30)", false},
+		{"T24(2023-06-15T24:00Z)", false},
+	}
+	for _, test := range tests {
+		if _, err := parseTimeToken(test.input); (err == nil) != test.valid {
+			t.Errorf("parseTimeToken(%q) error = %v, want valid %v", test.input, err, test.valid)
+		}
+	}
+}
+
+func TestDayShifts(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"overnight", "T24(2023-06-15T21:30-07:00) T24(2023-06-16T15:40+01:00)", "21:30 (-07:00) 15:40 (+01:00) +1"},
+		{"same instant, next local day", "T24(2023-06-15T23:30Z) T24(2023-06-16T01:30+02:00)", "23:30 (+00:00) 01:30 (+02:00) +1"},
+		{"date line", "T12(2023-06-20T23:55+12:00) T12(2023-06-20T06:10-10:00)", "11:55PM (+12:00) 06:10AM (-10:00)"},
+		{"back a day", "T24(2023-06-21T01:00+12:00) T24(2023-06-20T06:10-10:00)", "01:00 (+12:00) 06:10 (-10:00) -1"},
+		{"after a date", "D(2023-06-15T00:00Z) T24(2023-06-16T01:00Z)", "15 Jun 2023 01:00 (+00:00) +1"},
+		{"dates are not marked", "T24(2023-06-15T10:00Z) D(2023-06-16T00:00Z)", "10:00 (+00:00) 16 Jun 2023"},
+		{"two days", "T24(2023-06-15T10:00Z) T24(2023-06-17T10:00Z)", "10:00 (+00:00) 10:00 (+00:00) +2"},
+		{"another trip", "T24(2023-06-15T10:00Z) T24(2023-06-25T10:00Z)", "10:00 (+00:00) 10:00 (+00:00)"},
+		{"new paragraph", "T24(2023-06-15T10:00Z)\n\nT24(2023-06-16T10:00Z)", "10:00 (+00:00)\n\n10:00 (+00:00)"},
+		{"new paragraph with spaces", "T24(2023-06-15T10:00Z)\r\n \r\nT24(2023-06-16T10:00Z)", "10:00 (+00:00)\r\n \r\n10:00 (+00:00)"},
+		{"invalid token between", "T24(2023-06-15T10:00Z) T24(2023-06-16T10:00+15:00) T24(2023-06-16T11:00Z)", "10:00 (+00:00) T24(2023-06-16T10:00+15:00) 11:00 (+00:00) +1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := placeTimes(test.input); got != test.want {
+				t.Errorf("placeTimes(%q) = %q, want %q", test.input, got, test.want)
+			}
+		})
+	}
+	if got := placeTimesHTML("T24(2023-06-15T21:30-07:00) T24(2023-06-16T15:40+01:00)"); got != "<em>21:30 (-07:00)</em> <em>15:40 (+01:00)<sup>+1</sup></em>" {
+		t.Errorf("placeTimesHTML of an overnight flight = %q", got)
+	}
+}
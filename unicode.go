@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Set with --ascii, transliterates the output for legacy systems
+var asciiOutput bool
+
+// Letters and punctuation that don't decompose into ASCII plus accents
+var asciiReplacements = strings.NewReplacer(
+	"ß", "ss", "Æ", "AE", "æ", "ae", "Œ", "OE", "œ", "oe",
+	"Ø", "O", "ø", "o", "Ł", "L", "ł", "l", "Đ", "D", "đ", "d",
+	"Þ", "Th", "þ", "th", "Ð", "D", "ð", "d", "ı", "i", "Ħ", "H", "ħ", "h",
+	"‘", "'", "’", "'", "‚", "'", "“", "\"", "”", "\"", "„", "\"",
+	"–", "-", "—", "-", "−", "-", "…", "...", "\u00a0", " ",
+)
+
+// Describes what is wrong with a lookup field, or returns "" when it is fine
+func textProblem(input string) string {
+	if !utf8.ValidString(input) {
+		return "has an invalid UTF-8 byte sequence"
+	}
+	//Replacement characters are left behind by an earlier broken conversion
+	if strings.ContainsRune(input, utf8.RuneError) {
+		return "has replacement characters (U+FFFD) from a broken conversion"
+	}
+	for _, r := range input {
+		if unicode.IsControl(r) {
+			return "has control characters"
+		}
+	}
+	return ""
+}
+
+func validString(input string) bool {
+	return textProblem(input) == ""
+}
+
+func normalizeText(input string) string {
+	//The same name may be typed precomposed or with combining accents
+	return norm.NFC.String(input)
+}
+
+func transliterate(input string) string {
+	input = asciiReplacements.Replace(input)
+
+	//Split letters from their accents and drop the accents
+	stripMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	input, _, _ = transform.String(stripMarks, input)
+
+	//Whatever is left outside ASCII can't be represented
+	return strings.Map(func(r rune) rune {
+		if r > 127 {
+			return '?'
+		}
+		return r
+	}, input)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		//Accents are dropped from their letters
+		{"Zürich", "Zurich"},
+		{"São Paulo–Guarulhos", "Sao Paulo-Guarulhos"},
+		{"Reykjavík Keflavík", "Reykjavik Keflavik"},
+		{"Zu\u0308rich", "Zurich"},
+		//Ligatures and letters without a decomposition are spelled out
+		{"Straße", "Strasse"},
+		{"Ærø Œuvre", "AEro OEuvre"},
+		{"Łódź", "Lodz"},
+		{"Þingeyri", "Thingeyri"},
+		{"“Quoted” ‘text’…", "\"Quoted\" 'text'..."},
+		//Anything else can't be written in ASCII
+		{"東京国際空港", "??????"},
+		{"Αθήνα", "?????"},
+		{"Plain ASCII", "Plain ASCII"},
+	}
+	for _, test := range tests {
+		if got := transliterate(test.input); got != test.want {
+			t.Errorf("transliterate(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	//Combining accents become the precomposed letter, so both spellings compare equal
+	if got := normalizeText("Zu\u0308rich"); got != "Z\u00fcrich" {
+		t.Errorf("normalizeText(%q) = %q, want %q", "Zu\u0308rich", got, "Z\u00fcrich")
+	}
+	if got := normalizeText("Z\u00fcrich"); got != "Z\u00fcrich" {
+		t.Errorf("normalizeText changed the precomposed %q into %q", "Z\u00fcrich", got)
+	}
+}
+
+func TestASCIIOutput(t *testing.T) {
+	dir := t.TempDir()
+	lookup := filepath.Join(dir, "airports.csv")
+	content := "name,iso_country,municipality,icao_code,iata_code,coordinates\n" +
+		"Zürich Airport,CH,Zürich,LSZH,ZRH,\"8.549, 47.465\"\n"
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(lookup, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(input, []byte("Flight to #ZRH in *#ZRH\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { lookupFlags, asciiOutput, diagnostics = nil, false, nil }()
+
+	for _, test := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"convert", "--lookup", lookup, input, filepath.Join(dir, "plain.txt")}, "Flight to Zürich Airport in Zürich"},
+		{[]string{"convert", "--ascii", "--lookup", lookup, input, filepath.Join(dir, "ascii.txt")}, "Flight to Zurich Airport in Zurich"},
+	} {
+		lookupFlags, asciiOutput = nil, false
+		if exit := runCommand(test.args); exit != exitOK {
+			t.Fatalf("%q exited with %d", test.args, exit)
+		}
+		output, err := os.ReadFile(test.args[len(test.args)-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(output) != test.want {
+			t.Errorf("%q wrote %q, want %q", test.args, output, test.want)
+		}
+		if strings.Contains(test.want, "Zurich") && strings.ContainsFunc(string(output), func(r rune) bool { return r > 127 }) {
+			t.Errorf("--ascii output %q is not ASCII", output)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// How far a code may be from an unresolved one to be suggested
+const (
+	maxSuggestionDistance = 2
+	maxSuggestions        = 3
+)
+
+type suggestion struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type unresolvedCode struct {
+	Kind        string //airport or airline
+	Code        string
+	Line        int
+	Column      int
+	Suggestions []suggestion
+}
+
+func editDistance(a, b string) int {
+	//Levenshtein distance with a single row
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func suggestAirports(code string) []suggestion {
+	icao := strings.HasPrefix(code, "##")
+	wanted := strings.TrimLeft(code, "#")
+
+	type candidate struct {
+		airport  Airport
+		distance int
+	}
+
+	//Rank codes of the same kind by edit distance
+	var candidates []candidate
+	for _, airport := range airports {
+		known := airport.IATA_Code
+		if icao {
+			known = airport.ICAO_Code
+		}
+		if distance := editDistance(wanted, known); distance <= maxSuggestionDistance {
+			candidates = append(candidates, candidate{airport, distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	formatCode := func(airport Airport) string {
+		if icao {
+			return "##" + airport.ICAO_Code
+		}
+		return "#" + airport.IATA_Code
+	}
+
+	var suggestions []suggestion
+	seen := map[string]bool{}
+	add := func(airport Airport) {
+		if !seen[formatCode(airport)] && len(suggestions) < maxSuggestions {
+			seen[formatCode(airport)] = true
+			suggestions = append(suggestions, suggestion{Code: formatCode(airport), Name: airport.Name})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	//The best match first, then other airports serving the same city, then the next closest codes
+	best := candidates[0].airport
+	add(best)
+	for _, airport := range airports {
+		if airport.Municipality == best.Municipality && airport.ISO_Country == best.ISO_Country {
+			add(airport)
+		}
+	}
+	for _, c := range candidates[1:] {
+		add(c.airport)
+	}
+	return suggestions
+}
+
+func findUnresolvedCodes(input string) []unresolvedCode {
+	re := regexp.MustCompile(`##[A-Z]{4}|#[A-Z]{3}|` + flightToken.String())
+
+	var unresolved []unresolvedCode
+	for lineIndex, line := range strings.Split(replaceLineBreaks(input), "\n") {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			start, end := loc[0], loc[1]
+
+			//Codes glued to other text are not converted, so they can't be unresolved either
+			if start > 0 && (isAlphaNumeric(rune(line[start-1])) || line[start-1] == '@') {
+				continue
+			}
+			if end < len(line) && isAlphaNumeric(rune(line[end])) {
+				continue
+			}
+
+			code := line[start:end]
+			found := unresolvedCode{Kind: "airport", Code: code, Line: lineIndex + 1, Column: len([]rune(line[:start])) + 1}
+			if strings.HasPrefix(code, "@") {
+				//Flight numbers are reported by their airline code
+				airlineCode, _, icao := splitFlight(code)
+				if _, _, known := findAirlineByToken(code); known || !airlineCodeShape(airlineCode) {
+					continue
+				}
+				found.Kind = "airline"
+				found.Code = "@" + airlineCode
+				if icao {
+					found.Code = "@@" + airlineCode
+				}
+				found.Suggestions = suggestAirlines(code)
+			} else {
+				if _, known := findAirportByCode(code); known {
+					continue
+				}
+				found.Suggestions = suggestAirports(code)
+			}
+			unresolved = append(unresolved, found)
+		}
+	}
+	return unresolved
+}
+
+// Codes of an itinerary the lookups don't know. Written text is searched like markup,
+// the segments of a booking by their fields on the line of the segment
+func findUnresolvedInItinerary(read Itinerary) []unresolvedCode {
+	var unresolved []unresolvedCode
+	read.eachParagraph(func(text string, line int, segment *Segment) {
+		if text != "" {
+			for _, code := range findUnresolvedCodes(text) {
+				if line > 0 {
+					code.Line += line - 1
+				} else {
+					code.Line, code.Column = 0, 0
+				}
+				unresolved = append(unresolved, code)
+			}
+			return
+		}
+
+		at := unresolvedCode{Kind: "airport", Line: segment.Line}
+		if segment.Line > 0 {
+			at.Column = 1
+		}
+		for _, code := range []string{segment.From, segment.To} {
+			if _, known := findAirportByCode(code); !known {
+				found := at
+				found.Code, found.Suggestions = code, suggestAirports(code)
+				unresolved = append(unresolved, found)
+			}
+		}
+		if _, known := segment.airlineName(); !known && segment.Airline != "" {
+			found := at
+			found.Kind, found.Code, found.Suggestions = "airline", segment.Airline, suggestAirlines(segment.Airline+segment.Number)
+			unresolved = append(unresolved, found)
+		}
+	})
+	return unresolved
+}
+
+func reportUnresolvedCodes(path string, unresolved []unresolvedCode, sev severity) {
+	for _, code := range unresolved {
+		report(Diagnostic{
+			Code:        diagCodeUnresolved,
+			Severity:    sev,
+			File:        path,
+			Line:        code.Line,
+			Column:      code.Column,
+			Message:     "unknown " + code.Kind + " code " + code.Code,
+			Suggestions: code.Suggestions,
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"LHR", "LHR", 0},
+		{"LHR", "LGR", 1},
+		{"LHR", "HLR", 2},
+		{"LHR", "JFK", 3},
+		{"EGLL", "EGL", 1},
+		{"", "LAX", 3},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSuggestAirports(t *testing.T) {
+	defer func() { airports = nil }()
+	airports = []Airport{
+		{Name: "London Heathrow Airport", Municipality: "London", ISO_Country: "GB", IATA_Code: "LHR", ICAO_Code: "EGLL"},
+		{Name: "London City Airport", Municipality: "London", ISO_Country: "GB", IATA_Code: "LCY", ICAO_Code: "EGLC"},
+		{Name: "London Gatwick Airport", Municipality: "London", ISO_Country: "GB", IATA_Code: "LGW", ICAO_Code: "EGKK"},
+		{Name: "London Stansted Airport", Municipality: "London", ISO_Country: "GB", IATA_Code: "STN", ICAO_Code: "EGSS"},
+		{Name: "Lahore Allama Iqbal Airport", Municipality: "Lahore", ISO_Country: "PK", IATA_Code: "LHE", ICAO_Code: "OPLA"},
+		{Name: "Los Angeles International Airport", Municipality: "Los Angeles", ISO_Country: "US", IATA_Code: "LAX", ICAO_Code: "KLAX"},
+		{Name: "Lihue Airport", Municipality: "Lihue", ISO_Country: "US", IATA_Code: "LIH", ICAO_Code: "PHLI"},
+	}
+	tests := []struct {
+		code string
+		want []string
+	}{
+		//A one letter typo is found first, then the other airports of its city, up to maxSuggestions
+		{"#LHX", []string{"#LHR", "#LCY", "#LGW"}},
+		{"##EGLX", []string{"##EGLL", "##EGLC", "##EGKK"}},
+		{"#SAX", []string{"#LAX", "#STN"}},
+		//Nothing within two edits
+		{"#ZZZ", nil},
+		{"##ZZZZ", nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, suggestion := range suggestAirports(test.code) {
+			got = append(got, suggestion.Code)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("suggestAirports(%q) = %v, want %v", test.code, got, test.want)
+		}
+	}
+
+	//However many airports are close, no more than maxSuggestions are offered
+	if got := suggestAirports("#LXX"); len(got) != maxSuggestions {
+		t.Errorf("suggestAirports(#LXX) gave %v suggestions, want %v: %v", len(got), maxSuggestions, got)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+// Simplified land masses used as the background of the route map.
+// Every polygon is a list of {longitude, latitude} points.
+var worldOutline = [][][2]float64{
+	//North America
+	{{-168, 66}, {-162, 70}, {-156, 71.3}, {-140, 69.6}, {-128, 70}, {-115, 68.5}, {-95, 72}, {-82, 73}, {-80, 68}, {-85, 66}, {-95, 60}, {-92, 57}, {-82, 55}, {-79, 52}, {-78, 60}, {-70, 61}, {-64, 60}, {-60, 55}, {-56, 52}, {-66, 45}, {-70, 43}, {-71, 41}, {-76, 38}, {-75, 35}, {-81, 31}, {-80, 25.5}, {-82, 27}, {-84, 30}, {-90, 29}, {-97, 27.5}, {-97, 21}, {-92, 18.5}, {-87, 21.5}, {-88, 16}, {-83, 15}, {-83.5, 10}, {-79.5, 9}, {-77.5, 8}, {-81, 7.5}, {-86, 11}, {-92, 14.5}, {-105, 20}, {-106, 23}, {-112, 29}, {-114.5, 31}, {-110, 23}, {-115, 30}, {-118, 34}, {-121, 35}, {-124, 40}, {-124, 47}, {-127, 50}, {-131, 55}, {-136, 58}, {-141, 60}, {-150, 61}, {-154, 58}, {-158, 56}, {-164, 54.5}, {-158, 58}, {-162, 60}, {-166, 62}, {-165, 65}},
+	//South America
+	{{-77.5, 8}, {-72, 12}, {-63, 10.5}, {-60, 8}, {-52, 5}, {-50, 0}, {-44, -2.5}, {-35, -5}, {-35, -9}, {-39, -15}, {-40, -22}, {-44, -23}, {-48, -26}, {-53, -34}, {-57, -36}, {-62, -39}, {-65, -42}, {-65, -47}, {-68, -50}, {-68.5, -53}, {-66, -55}, {-71, -55}, {-75, -50}, {-73, -42}, {-73.5, -37}, {-71.5, -30}, {-70, -18}, {-76, -14}, {-81, -6}, {-80, -1}, {-77, 3}},
+	//Eurasia
+	{{-9, 43}, {-9, 37}, {-6, 36.5}, {-2, 36.7}, {0.5, 38.5}, {3, 42}, {6, 43}, {9, 44.3}, {12, 42}, {15.5, 38}, {16, 40.5}, {18.5, 40}, {13, 45.5}, {19.5, 41.5}, {22.5, 37}, {24, 40.5}, {26.5, 40.5}, {27, 37}, {30, 36.5}, {36, 36.5}, {35.5, 33}, {34, 31.5}, {32.5, 30}, {34.5, 28}, {38, 24}, {42.5, 16}, {43.5, 12.7}, {45, 13}, {52, 16}, {55.5, 17.5}, {58.5, 20.5}, {59.8, 22.5}, {56.5, 26}, {54, 24}, {51.5, 25}, {50, 26.5}, {48, 29.5}, {50, 30}, {54, 26.8}, {57, 27}, {61.5, 25}, {66.5, 25.5}, {68.5, 23.5}, {72.5, 21}, {73, 16}, {75, 12}, {77.5, 8}, {80, 10}, {80.3, 15.5}, {82, 17}, {87, 21}, {91.5, 22.5}, {94, 19}, {94.5, 16}, {97.5, 16.5}, {98, 10}, {100.5, 7}, {103.5, 1.3}, {101, 3}, {100.3, 6.5}, {99.5, 9.5}, {100, 13.5}, {105, 8.6}, {109, 11.5}, {108.5, 16}, {106.5, 19.5}, {110, 21.5}, {114, 22.3}, {118, 24.5}, {121.5, 30}, {120.5, 34}, {119, 36.5}, {122.5, 37.2}, {118, 38.5}, {121.5, 40.9}, {124.5, 40}, {126.5, 37.5}, {126.5, 34.5}, {129.3, 35.2}, {129.5, 41}, {132, 43}, {135.5, 43.5}, {140.5, 48.5}, {141.5, 53}, {138, 54}, {135, 55}, {141, 59}, {150, 59.5}, {155, 59}, {156.5, 57}, {156, 51}, {160, 53}, {163, 56}, {163, 60}, {170, 60}, {180, 65}, {180, 69}, {170, 70}, {160, 70}, {150, 71.5}, {140, 72.5}, {130, 71}, {125, 73.5}, {113, 73.7}, {110, 76.5}, {104, 77.7}, {98, 76}, {89, 75.5}, {80, 73.5}, {75, 72.5}, {72.5, 69}, {66, 69}, {60, 69.8}, {54, 68.5}, {44, 68.5}, {41, 66.5}, {34, 69.3}, {25, 71}, {16, 69}, {12, 65.5}, {5, 62}, {5.5, 58.5}, {8, 58}, {10.5, 59.5}, {11.5, 58}, {12.5, 56}, {14, 55.5}, {18, 57}, {17, 61}, {21.5, 64}, {25, 65.5}, {22, 60.5}, {29, 60}, {23, 59}, {21, 57}, {21, 55}, {14, 54}, {10, 54.5}, {8.5, 55.5}, {8, 53.5}, {5, 53}, {3, 51.5}, {1.5, 50.5}, {-1.5, 49.5}, {-4.7, 48.5}, {-1.5, 46.5}, {-1.5, 43.5}},
+	//Chukotka, east of the antimeridian
+	{{-180, 65}, {-172, 64.5}, {-169.5, 66}, {-175, 67.5}, {-180, 69}},
+	//Africa
+	{{-17, 21}, {-16, 14.7}, {-17, 12.5}, {-13.5, 9}, {-11, 7}, {-7.5, 4.4}, {-2, 4.7}, {2, 6.2}, {5, 5.5}, {7, 4.4}, {9.5, 3.8}, {9.5, 1}, {9, -1}, {12, -5}, {13.5, -11}, {12, -17}, {14.5, -22.5}, {16.5, -28.5}, {18.5, -34}, {20, -34.8}, {25.5, -34}, {30, -31}, {33, -26}, {35.5, -23.5}, {35, -19.5}, {40.5, -15}, {40, -10}, {39.5, -5}, {41.5, -1.5}, {46, 2.2}, {51, 10.5}, {51.2, 12}, {45, 10.5}, {43.3, 11.7}, {43, 13}, {39.5, 15.5}, {37.5, 18.5}, {35.5, 23.5}, {34, 27}, {32.5, 30}, {29, 30.9}, {25, 31.6}, {20, 30.9}, {19.5, 32.2}, {15, 32.3}, {11, 33.3}, {10.5, 36.8}, {9.5, 37.3}, {3, 36.8}, {-2, 35}, {-5.9, 35.8}, {-9.8, 31}, {-10, 29}, {-13, 27.5}},
+	//Australia
+	{{113.5, -22}, {114, -26}, {115, -34}, {118, -35}, {123.5, -34}, {129, -31.6}, {134, -32.5}, {138, -35.5}, {140, -38}, {146.5, -39}, {150, -37.5}, {153.5, -28}, {153, -25}, {146, -19}, {145.3, -15}, {142.5, -10.7}, {141.5, -13}, {141.5, -17}, {140, -17.5}, {136, -15.5}, {137, -12.2}, {132.5, -11.5}, {130, -13}, {129.3, -15}, {126, -14}, {122, -17.5}, {119, -20}},
+	//Greenland
+	{{-73, 78}, {-66, 81}, {-50, 82.5}, {-30, 83.5}, {-20, 82}, {-18, 77}, {-20, 70}, {-25, 68.5}, {-32, 68}, {-40, 65}, {-43, 60}, {-48, 61}, {-51, 64}, {-53, 66.5}, {-54, 70}, {-58, 75.5}, {-68, 76.5}},
+	//Great Britain
+	{{-5.7, 50}, {1.5, 51.2}, {1.7, 52.7}, {0, 53.5}, {-1.5, 55}, {-2, 57}, {-1.8, 57.6}, {-4, 58.6}, {-5, 58.6}, {-6.2, 56.7}, {-5, 55.5}, {-3, 54.9}, {-3.3, 53.4}, {-4.7, 52.8}, {-5, 51.6}, {-3, 51.4}},
+	//Ireland
+	{{-10, 51.6}, {-6, 52}, {-6, 54}, {-8, 55.3}, {-10, 54.2}},
+	//Iceland
+	{{-24, 65.5}, {-18, 66.5}, {-14, 66}, {-13.5, 65}, {-18, 63.5}, {-22.5, 63.8}},
+	//Japan
+	{{130, 31}, {131, 31.4}, {132, 33}, {135, 33.5}, {137, 34.5}, {140, 35}, {141, 38}, {142, 40}, {141.4, 41.5}, {143, 42}, {145.5, 43.3}, {142, 45.5}, {140, 43.3}, {140, 41}, {139.7, 40}, {139.5, 38}, {136.5, 37}, {136, 35.7}, {133, 35.5}, {131, 34.5}, {130, 33.5}},
+	//Cuba
+	{{-85, 21.8}, {-80, 23}, {-74.2, 20.2}, {-77.5, 19.9}, {-81, 21.6}},
+	//Madagascar
+	{{49, -12}, {50.5, -15.5}, {49.5, -18}, {47, -25}, {45, -25.5}, {43.7, -22}, {44.3, -16}, {47, -15}},
+	//Sumatra
+	{{95.3, 5.6}, {98, 4}, {104, -1.5}, {106, -5.8}, {102, -4}, {100, -1}},
+	//Borneo
+	{{109, 1.5}, {111, -3}, {116, -4}, {118, 1}, {119, 5.5}, {116, 7}, {113, 3}},
+	//New Guinea
+	{{131, -1.5}, {135, -3.5}, {141, -2.6}, {145, -4.5}, {150.5, -10.5}, {147, -10}, {143, -9}, {138, -8.3}, {136, -4.5}},
+	//New Zealand
+	{{172.7, -34.4}, {174.5, -36}, {178.5, -37.6}, {177, -39.5}, {175, -41.5}, {172.5, -41}, {171, -42.5}, {168, -46.5}, {166.5, -46}, {168.5, -44}, {171.5, -41.5}, {173, -40.5}, {174.5, -37.5}},
+	//Antarctica
+	{{-180, -90}, {-180, -78}, {-150, -76}, {-120, -73}, {-90, -72}, {-60, -64}, {-58, -63}, {-62, -66}, {-75, -70}, {-60, -75}, {-30, -77}, {0, -70}, {30, -69}, {60, -67}, {90, -66}, {120, -66}, {150, -68}, {170, -72}, {180, -78}, {180, -90}},
+}